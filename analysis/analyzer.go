@@ -0,0 +1,137 @@
+// Package analysis 在jieba分词的基础上提供Lucene风格的分析流程: 字符过滤器 -> 分词器 -> 词过滤器.
+// 字符过滤器修改文本时记录偏移修正, 最终token的偏移仍然对应原文.
+package analysis
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/jieba"
+)
+
+// TypeWord 普通词的类型, 识别器识别出的片段使用识别器的类型, 如 url, email
+const TypeWord = "word"
+
+// Token 分析得到的词
+type Token struct {
+	// Term 用于索引的词
+	Term string
+	// Text 原文中的文本
+	Text string
+	// Start/End 在原文中的rune偏移, ByteStart/ByteEnd 在原文中的字节偏移
+	Start     int
+	End       int
+	ByteStart int
+	ByteEnd   int
+	Pos       string
+	Type      string
+	// PositionIncrement 相对前一个token的位置增量, 删除token时累加到下一个token上
+	PositionIncrement int
+	// PositionLength 占据的位置个数
+	PositionLength int
+}
+
+func (t *Token) String() string {
+	return fmt.Sprintf("['%s', %d, %d, '%s']", t.Term, t.Start, t.End, t.Type)
+}
+
+// Tokenizer 把字符过滤后的文本切分为token, token的偏移对应输入的文本
+type Tokenizer interface {
+	Tokenize(text string) []*Token
+}
+
+// TokenizerFunc 把函数作为Tokenizer
+type TokenizerFunc func(text string) []*Token
+
+func (f TokenizerFunc) Tokenize(text string) []*Token {
+	return f(text)
+}
+
+// NewJiebaTokenizer 使用jieba分词, opts为nil时使用默认选项. ModeIndex的子词和原词组成token图, PositionLength同SegToken
+func NewJiebaTokenizer(handler *jieba.SegmentHandler, opts *jieba.SegOptions) Tokenizer {
+	return TokenizerFunc(func(text string) []*Token {
+		segTokens := handler.SegParagraphWithOptions(text, opts)
+		tokens := make([]*Token, 0, len(segTokens))
+		prev := -1
+		for _, st := range segTokens {
+			typ := st.Type
+			if typ == "" {
+				typ = TypeWord
+			}
+			tokens = append(tokens, &Token{
+				Term:              st.Word,
+				Text:              st.Text,
+				Start:             st.Start,
+				End:               st.End,
+				ByteStart:         st.ByteStart,
+				ByteEnd:           st.ByteEnd,
+				Pos:               st.Pos,
+				Type:              typ,
+				PositionIncrement: st.Position - prev,
+				PositionLength:    st.PositionLength,
+			})
+			prev = st.Position
+		}
+		return tokens
+	})
+}
+
+// Analyzer 依次执行字符过滤器、分词器和词过滤器, 可以并发调用
+type Analyzer struct {
+	CharFilters  []CharFilter
+	Tokenizer    Tokenizer
+	TokenFilters []TokenFilter
+}
+
+func (a *Analyzer) Analyze(text string) []*Token {
+	filtered := text
+	maps := make([]*OffsetMap, 0, len(a.CharFilters))
+	for _, f := range a.CharFilters {
+		var m *OffsetMap
+		filtered, m = f.Filter(filtered)
+		maps = append(maps, m)
+	}
+	tokens := a.Tokenizer.Tokenize(filtered)
+	if len(a.CharFilters) > 0 {
+		correctOffsets(tokens, text, maps)
+	}
+	for _, f := range a.TokenFilters {
+		tokens = f.Filter(tokens)
+	}
+	return tokens
+}
+
+// correctOffsets 把token在过滤后文本中的偏移修正为原文的偏移
+func correctOffsets(tokens []*Token, text string, maps []*OffsetMap) {
+	runeIndex := make([]int, len(text)+1)
+	n := 0
+	for i := 0; i < len(text); n++ {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		for end := i + size; i < end; i++ {
+			runeIndex[i] = n
+		}
+	}
+	runeIndex[len(text)] = n
+
+	for _, token := range tokens {
+		start, end := token.ByteStart, token.ByteEnd
+		for k := len(maps) - 1; k >= 0; k-- {
+			start, end = maps[k].CorrectStart(start), maps[k].CorrectEnd(end)
+		}
+		start = clamp(start, 0, len(text))
+		end = clamp(end, start, len(text))
+		token.ByteStart, token.ByteEnd = start, end
+		token.Start, token.End = runeIndex[start], runeIndex[end]
+		token.Text = text[start:end]
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/jieba"
+)
+
+func loadTestHandler(t *testing.T) *jieba.SegmentHandler {
+	fsys := fstest.MapFS{}
+	for name, fp := range map[string]string{
+		jieba.BaseDictName:                   "../test/jieba/dict.txt",
+		jieba.UserDictDirName + "/user.dict": "../test/jieba/user/user.dict",
+		jieba.BaseProbName:                   "../dict/prob_emit.txt",
+	} {
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	handler, err := jieba.NewSegmentHandlerFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler
+}
+
+func terms(tokens []*Token) string {
+	var items []string
+	for _, token := range tokens {
+		items = append(items, fmt.Sprintf("%s/%s/%d", token.Term, token.Text, token.PositionIncrement))
+	}
+	return strings.Join(items, " ")
+}
+
+func TestAnalyzer(t *testing.T) {
+	handler := loadTestHandler(t)
+	stopWords, err := LoadStopWords(os.DirFS("../dict"), jieba.IdfStopWordsName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := &Analyzer{
+		CharFilters: []CharFilter{HTMLStripCharFilter},
+		Tokenizer:   NewJiebaTokenizer(handler, &jieba.SegOptions{DisableHmm: true}),
+		TokenFilters: []TokenFilter{
+			PunctuationFilter,
+			NewStopFilter(stopWords),
+			NewLengthFilter(2, 0),
+		},
+	}
+	text := "<p>我来到<b>北京</b>清华大学</p><script>var a = 1;</script>&lt;中国&gt;"
+	tokens := analyzer.Analyze(text)
+	fmt.Println(tokens)
+	expect := "来到/来到/3 北京/北京/1 清华大学/清华大学/1 中国/中国/3"
+	if got := terms(tokens); got != expect {
+		t.Errorf("expect %s, got %s", expect, got)
+	}
+	for _, token := range tokens {
+		if text[token.ByteStart:token.ByteEnd] != token.Text || string([]rune(text)[token.Start:token.End]) != token.Text {
+			t.Errorf("bad offsets %+v", token)
+		}
+	}
+}
+
+func TestCharFilters(t *testing.T) {
+	cases := []struct {
+		filter CharFilter
+		input  string
+		expect string
+	}{
+		{HTMLStripCharFilter, "a<br/>b<!-- c -->d&amp;e&#20013;<STYLE>x</style>f<不是标签", "a\nbd&e中f<不是标签"},
+		{HTMLStripCharFilter, "1 < 2 && 3 > 2", "1 < 2 && 3 > 2"},
+		{NewMappingCharFilter(map[string]string{"①": "1", "⑩": "10", "“": "\"", "”": "\"", "ﬁ": "fi"}), "“①⑩”ﬁ", "\"110\"fi"},
+	}
+	for _, c := range cases {
+		got, _ := c.filter.Filter(c.input)
+		if got != c.expect {
+			t.Errorf("%q: expect %q, got %q", c.input, c.expect, got)
+		}
+	}
+
+	// 替换的片段映射回原文的整个片段, 删除的片段不包含在前后的token中
+	text := "x&amp;<b>y</b>z"
+	filtered, m := HTMLStripCharFilter.Filter(text)
+	if filtered != "x&yz" {
+		t.Fatalf("bad filtered %q", filtered)
+	}
+	spans := [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}}
+	expect := []string{"x", "&amp;", "y", "z"}
+	for i, span := range spans {
+		start, end := m.CorrectStart(span[0]), m.CorrectEnd(span[1])
+		if text[start:end] != expect[i] {
+			t.Errorf("%v: expect %s, got %s", span, expect[i], text[start:end])
+		}
+	}
+}
+
+func TestTokenFilters(t *testing.T) {
+	handler := loadTestHandler(t)
+	tokenizer := NewJiebaTokenizer(handler, &jieba.SegOptions{DisableHmm: true, KeepCase: true, Recognizers: jieba.DefaultRecognizers()})
+
+	analyzer := &Analyzer{Tokenizer: tokenizer, TokenFilters: []TokenFilter{LowercaseFilter, NewTypeFilter(false, jieba.TypeURL, jieba.TypeEmail)}}
+	tokens := analyzer.Analyze("访问HTTPS://EXAMPLE.COM或者写信给A@B.CN的Apple")
+	fmt.Println(tokens)
+	if got := terms(tokens); got != "访/访/1 问/问/1 或/或/2 者/者/1 写/写/1 信/信/1 给/给/1 的/的/2 apple/Apple/1" {
+		t.Errorf("bad tokens %s", got)
+	}
+
+	analyzer = &Analyzer{Tokenizer: tokenizer, TokenFilters: []TokenFilter{NewTypeFilter(true, jieba.TypeURL), NewLengthFilter(1, 10)}}
+	if tokens = analyzer.Analyze("见https://example.com/a和www.a.cn"); terms(tokens) != "www.a.cn/www.a.cn/4" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+
+	analyzer = &Analyzer{Tokenizer: tokenizer, TokenFilters: []TokenFilter{NewPosFilter(true, "ns", "nt")}}
+	if tokens = analyzer.Analyze("我来到北京清华大学"); terms(tokens) != "北京/北京/3 清华大学/清华大学/1" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
@@ -0,0 +1,214 @@
+package analysis
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharFilter 在分词前修改文本, 返回修改后的文本和偏移修正, 没有修改偏移时可以返回nil
+type CharFilter interface {
+	Filter(text string) (string, *OffsetMap)
+}
+
+// CharFilterFunc 把函数作为CharFilter
+type CharFilterFunc func(text string) (string, *OffsetMap)
+
+func (f CharFilterFunc) Filter(text string) (string, *OffsetMap) {
+	return f(text)
+}
+
+// OffsetMap 过滤后文本的字节偏移到过滤前字节偏移的映射.
+// 由若干修正点组成, 两个修正点之间的文本是原样复制的, 偏移差不变
+type OffsetMap struct {
+	outs []int
+	ins  []int
+}
+
+// Add 添加修正点, 过滤后的偏移out对应过滤前的偏移in, out需要非递减.
+// 同一个out可以有多个修正点, 例如删除的片段前后, 起始偏移使用最后一个, 结束偏移使用第一个
+func (m *OffsetMap) Add(out, in int) {
+	m.outs = append(m.outs, out)
+	m.ins = append(m.ins, in)
+}
+
+// CorrectStart 修正token的起始偏移
+func (m *OffsetMap) CorrectStart(out int) int {
+	if m == nil {
+		return out
+	}
+	i := sort.SearchInts(m.outs, out+1) - 1
+	if i < 0 {
+		return out
+	}
+	return m.ins[i] + out - m.outs[i]
+}
+
+// CorrectEnd 修正token的结束偏移
+func (m *OffsetMap) CorrectEnd(out int) int {
+	if m == nil {
+		return out
+	}
+	i := sort.SearchInts(m.outs, out)
+	if i < len(m.outs) && m.outs[i] == out {
+		return m.ins[i]
+	}
+	if i == 0 {
+		return out
+	}
+	return m.ins[i-1] + out - m.outs[i-1]
+}
+
+// replacer 复制文本时记录替换和删除的片段
+type replacer struct {
+	sb      strings.Builder
+	offsets *OffsetMap
+}
+
+func newReplacer(size int) *replacer {
+	r := &replacer{offsets: &OffsetMap{}}
+	r.sb.Grow(size)
+	return r
+}
+
+// replace 原文中从in开始、长度为n的片段替换为s, s为空时删除
+func (r *replacer) replace(in, n int, s string) {
+	r.offsets.Add(r.sb.Len(), in)
+	r.sb.WriteString(s)
+	r.offsets.Add(r.sb.Len(), in+n)
+}
+
+// htmlBlockTags 块级元素, 删除时替换为换行, 避免前后的文字连在一起分词
+var htmlBlockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "td": true, "th": true, "title": true, "tr": true,
+	"ul": true,
+}
+
+// htmlRawTags 内容不是文本的元素, 连同内容一起删除
+var htmlRawTags = []string{"script", "style"}
+
+const maxHtmlEntity = 32
+
+// HTMLStripCharFilter 删除html标签、注释以及script和style的内容, 解码字符实体, 块级元素替换为换行
+var HTMLStripCharFilter CharFilter = CharFilterFunc(stripHTML)
+
+func stripHTML(text string) (string, *OffsetMap) {
+	r := newReplacer(len(text))
+	for i := 0; i < len(text); {
+		switch text[i] {
+		case '<':
+			if n, s := htmlTag(text, i); n > 0 {
+				r.replace(i, n, s)
+				i += n
+				continue
+			}
+		case '&':
+			if end := strings.IndexByte(text[i:minInt(i+maxHtmlEntity, len(text))], ';'); end > 1 {
+				entity := text[i : i+end+1]
+				if s := html.UnescapeString(entity); s != entity {
+					r.replace(i, len(entity), s)
+					i += len(entity)
+					continue
+				}
+			}
+		}
+		r.sb.WriteByte(text[i])
+		i++
+	}
+	return r.sb.String(), r.offsets
+}
+
+// htmlTag text[i:]开始的标签或注释的长度和替换的文本, 不是标签时长度为0
+func htmlTag(text string, i int) (int, string) {
+	if strings.HasPrefix(text[i:], "<!--") {
+		end := strings.Index(text[i+4:], "-->")
+		if end < 0 {
+			return len(text) - i, ""
+		}
+		return end + 7, ""
+	}
+	j := i + 1
+	closing := j < len(text) && text[j] == '/'
+	if closing {
+		j++
+	}
+	nameStart := j
+	for j < len(text) && isTagNameByte(text[j]) {
+		j++
+	}
+	if j == nameStart && (j >= len(text) || text[j] != '!') {
+		return 0, ""
+	}
+	end := strings.IndexByte(text[j:], '>')
+	if end < 0 {
+		return 0, ""
+	}
+	end += j + 1
+	name := strings.ToLower(text[nameStart:j])
+	if !closing {
+		for _, raw := range htmlRawTags {
+			if name != raw {
+				continue
+			}
+			closeTag := "</" + raw
+			k := strings.Index(strings.ToLower(text[end:]), closeTag)
+			if k < 0 {
+				return len(text) - i, ""
+			}
+			k += end + len(closeTag)
+			if gt := strings.IndexByte(text[k:], '>'); gt >= 0 {
+				k += gt + 1
+			}
+			return k - i, ""
+		}
+	}
+	if htmlBlockTags[name] {
+		return end - i, "\n"
+	}
+	return end - i, ""
+}
+
+func isTagNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// NewMappingCharFilter 按mapping替换文本, 从左到右使用最长的匹配, 如 {"①": "1", "“": "\""}
+func NewMappingCharFilter(mapping map[string]string) CharFilter {
+	maxLen := 0
+	for key := range mapping {
+		if len(key) > maxLen {
+			maxLen = len(key)
+		}
+	}
+	return CharFilterFunc(func(text string) (string, *OffsetMap) {
+		r := newReplacer(len(text))
+		for i := 0; i < len(text); {
+			matched := false
+			for l := minInt(maxLen, len(text)-i); l > 0; l-- {
+				if s, ok := mapping[text[i:i+l]]; ok {
+					r.replace(i, l, s)
+					i += l
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				_, size := utf8.DecodeRuneInString(text[i:])
+				r.sb.WriteString(text[i : i+size])
+				i += size
+			}
+		}
+		return r.sb.String(), r.offsets
+	})
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,305 @@
+package analysis
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+// TypeSynonym 同义词过滤器加入的token的类型
+const TypeSynonym = "synonym"
+
+// SynonymMap 同义词表, 词和同义词都是分词后的词序列, 一个同义词可以对应多个token
+type SynonymMap struct {
+	root *synonymNode
+}
+
+type synonymNode struct {
+	children map[string]*synonymNode
+	// outputs 匹配到这里时输出的同义词, keepOrig 是否保留原来的词
+	outputs  [][]string
+	keepOrig bool
+	matched  bool
+}
+
+func newSynonymNode() *synonymNode {
+	return &synonymNode{children: map[string]*synonymNode{}}
+}
+
+// LoadSynonyms 从fsys加载Solr格式的同义词文件, 见ParseSynonyms
+func LoadSynonyms(fsys fs.FS, name string, analyzer *Analyzer, expand bool) (*SynonymMap, error) {
+	return LoadSynonymsWithOptions(fsys, name, analyzer, expand, nil)
+}
+
+// LoadSynonymsWithOptions 同LoadSynonyms, 通过opts选择严格或宽松模式, 见dictload.Options
+func LoadSynonymsWithOptions(fsys fs.FS, name string, analyzer *Analyzer, expand bool, opts *dictload.Options) (*SynonymMap, error) {
+	m := &SynonymMap{root: newSynonymNode()}
+	if _, err := dictload.Load(fsys, name, opts, m.parseLine(analyzer, expand)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseSynonyms 解析Solr格式的同义词, 每行一条规则, #开头的行是注释:
+//
+//	a, b, c    等价的词, expand为true时每个词扩展为所有的词, 否则都替换为第一个词
+//	a, b => c  a和b替换为c, 右边包含左边的词时保留原词
+//
+// 词中的逗号、等号和反斜杠使用反斜杠转义. 每个词使用analyzer分词得到词序列, analyzer需要和索引时同义词过滤器之前的处理一致.
+// 格式错误的行返回带行号的错误, 见dictload.LineError
+func ParseSynonyms(r io.Reader, analyzer *Analyzer, expand bool) (*SynonymMap, error) {
+	m := &SynonymMap{root: newSynonymNode()}
+	if _, err := dictload.LoadReader(r, "synonyms", nil, m.parseLine(analyzer, expand)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *SynonymMap) parseLine(analyzer *Analyzer, expand bool) func(line string) error {
+	return func(line string) error {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			return dictload.ErrSkip
+		}
+		sides := splitUnescaped(line, "=>")
+		if len(sides) > 2 {
+			return errors.New("more than one => in line:" + line)
+		}
+		left := m.phrases(sides[0], analyzer)
+		if len(left) == 0 {
+			return dictload.ErrSkip
+		}
+		if len(sides) == 2 {
+			right := m.phrases(sides[1], analyzer)
+			if len(right) == 0 {
+				return errors.New("empty synonyms in line:" + line)
+			}
+			for _, phrase := range left {
+				m.add(phrase, right)
+			}
+			return nil
+		}
+		for _, phrase := range left {
+			if expand {
+				m.add(phrase, left)
+			} else {
+				m.add(phrase, left[:1])
+			}
+		}
+		return nil
+	}
+}
+
+// phrases 逗号分开的词, 分词后去掉空白
+func (m *SynonymMap) phrases(side string, analyzer *Analyzer) [][]string {
+	var phrases [][]string
+	for _, item := range splitUnescaped(side, ",") {
+		var terms []string
+		for _, token := range analyzer.Analyze(unescape(strings.TrimSpace(item))) {
+			if strings.TrimFunc(token.Term, unicode.IsSpace) != "" {
+				terms = append(terms, token.Term)
+			}
+		}
+		if len(terms) > 0 {
+			phrases = append(phrases, terms)
+		}
+	}
+	return phrases
+}
+
+// add 添加规则, outputs中和phrase相同的词表示保留原词
+func (m *SynonymMap) add(phrase []string, outputs [][]string) {
+	node := m.root
+	for _, term := range phrase {
+		child, ok := node.children[term]
+		if !ok {
+			child = newSynonymNode()
+			node.children[term] = child
+		}
+		node = child
+	}
+	node.matched = true
+	for _, output := range outputs {
+		if equalTerms(output, phrase) {
+			node.keepOrig = true
+			continue
+		}
+		exists := false
+		for _, o := range node.outputs {
+			if equalTerms(o, output) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			node.outputs = append(node.outputs, output)
+		}
+	}
+}
+
+func equalTerms(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitUnescaped 按没有被反斜杠转义的sep切分
+func splitUnescaped(s, sep string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], sep) {
+			items = append(items, s[start:i])
+			i += len(sep) - 1
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// NewSynonymFilter 同义词过滤器, 从左到右使用最长的匹配, 同义词和原词从同一个位置开始.
+// 多个token的同义词依次占据连续的位置, 各条路径中最后一个token的PositionLength使所有路径在同一个位置结束,
+// 例如"个人 计算机"的同义词"电脑"的位置和"个人"相同, PositionLength为2, 短语查询在每条路径上都成立.
+// 匹配的词之间不能有删除的token(PositionIncrement大于1), 因此需要放在停用词等删除token的过滤器之前
+func NewSynonymFilter(synonyms *SynonymMap) TokenFilter {
+	return TokenFilterFunc(func(tokens []*Token) []*Token {
+		return synonyms.expand(tokens)
+	})
+}
+
+type positionedToken struct {
+	token    *Token
+	position int
+}
+
+func (m *SynonymMap) expand(tokens []*Token) []*Token {
+	positions := make([]int, len(tokens))
+	position := -1
+	for i, token := range tokens {
+		position += token.PositionIncrement
+		positions[i] = position
+	}
+
+	out := make([]positionedToken, 0, len(tokens))
+	expanded := false
+	// shift 同义词比原词长时增加的位置, 后面的token依次后移
+	shift := 0
+	for i := 0; i < len(tokens); {
+		end, node := m.match(tokens, i)
+		if node == nil {
+			out = append(out, positionedToken{token: tokens[i], position: positions[i] + shift})
+			i++
+			continue
+		}
+		expanded = true
+		first, last := tokens[i], tokens[end-1]
+		start := positions[i] + shift
+		span := positions[end-1] + shift + last.PositionLength - start
+		length := 0
+		if node.keepOrig {
+			length = span
+		}
+		for _, output := range node.outputs {
+			if len(output) > length {
+				length = len(output)
+			}
+		}
+		if node.keepOrig {
+			for k := i; k < end; k++ {
+				out = append(out, positionedToken{token: tokens[k], position: positions[k] + shift})
+			}
+			last.PositionLength += length - span
+		}
+		var text strings.Builder
+		for _, token := range tokens[i:end] {
+			text.WriteString(token.Text)
+		}
+		for _, output := range node.outputs {
+			for k, term := range output {
+				token := &Token{
+					Term:           term,
+					Text:           text.String(),
+					Start:          first.Start,
+					End:            last.End,
+					ByteStart:      first.ByteStart,
+					ByteEnd:        last.ByteEnd,
+					Pos:            first.Pos,
+					Type:           TypeSynonym,
+					PositionLength: 1,
+				}
+				if k == len(output)-1 {
+					token.PositionLength = length - k
+				}
+				out = append(out, positionedToken{token: token, position: start + k})
+			}
+		}
+		shift += length - span
+		i = end
+	}
+	if !expanded {
+		return tokens
+	}
+
+	// 按位置排序, 同一个位置原词在前
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].position < out[j].position
+	})
+	result := make([]*Token, len(out))
+	prev := -1
+	for i, pt := range out {
+		pt.token.PositionIncrement = pt.position - prev
+		prev = pt.position
+		result[i] = pt.token
+	}
+	return result
+}
+
+// match 从tokens[i]开始最长的匹配, 返回匹配结束的下标
+func (m *SynonymMap) match(tokens []*Token, i int) (int, *synonymNode) {
+	node := m.root
+	end := 0
+	var matched *synonymNode
+	for j := i; j < len(tokens); j++ {
+		if j > i && tokens[j].PositionIncrement != 1 {
+			break
+		}
+		child, ok := node.children[tokens[j].Term]
+		if !ok {
+			break
+		}
+		node = child
+		if node.matched {
+			end, matched = j+1, node
+		}
+	}
+	return end, matched
+}
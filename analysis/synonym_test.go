@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rolandhe/jiebag/jieba"
+)
+
+// positions 输出 词@位置:位置长度
+func positions(tokens []*Token) string {
+	var items []string
+	position := -1
+	for _, token := range tokens {
+		position += token.PositionIncrement
+		items = append(items, fmt.Sprintf("%s@%d:%d", token.Term, position, token.PositionLength))
+	}
+	return strings.Join(items, " ")
+}
+
+func TestSynonymFilter(t *testing.T) {
+	handler := loadTestHandler(t)
+	base := &Analyzer{Tokenizer: NewJiebaTokenizer(handler, &jieba.SegOptions{DisableHmm: true})}
+	rules := `# 注释
+中华人民共和国, 中国
+苹果手机 => iphone
+电脑, 个人计算机
+水果, 苹果 => 水果
+a\,b, ab
+`
+	synonyms, err := ParseSynonyms(strings.NewReader(rules), base, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzer := &Analyzer{
+		Tokenizer:    base.Tokenizer,
+		TokenFilters: []TokenFilter{NewSynonymFilter(synonyms), PunctuationFilter},
+	}
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		// 单个词的同义词
+		{"我爱中国", "我@0:1 爱@1:1 中国@2:1 中华人民共和国@2:1"},
+		// 多个词替换为一个词, 后面的token前移
+		{"买苹果手机", "买@0:1 iphone@1:1"},
+		// 同义词比原词长, 原词的最后一个token延长, 后面的token后移
+		{"电脑坏了", "电@0:1 个@0:1 脑@1:3 人@1:1 计算@2:1 机@3:1 坏@4:1 了@5:1"},
+		// 同义词比原词短
+		{"个人计算机", "个@0:1 电@0:1 人@1:1 脑@1:3 计算@2:1 机@3:1"},
+		{"苹果和水果", "水果@0:1 和@1:1 水果@2:1"},
+		{"a,b", "a@0:1 ab@0:3 b@2:1"},
+	}
+	for _, c := range cases {
+		tokens := analyzer.Analyze(c.input)
+		fmt.Println(tokens)
+		if got := positions(tokens); got != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.input, c.expect, got)
+		}
+	}
+
+	tokens := analyzer.Analyze("买苹果手机")
+	if tokens[1].Type != TypeSynonym || tokens[1].Text != "苹果手机" || tokens[1].Start != 1 || tokens[1].End != 5 {
+		t.Errorf("bad synonym token %+v", tokens[1])
+	}
+
+	if _, err = ParseSynonyms(strings.NewReader("# comment\na => b => c"), base, true); err == nil || !strings.HasPrefix(err.Error(), "synonyms:2:") {
+		t.Errorf("expect line error, got %v", err)
+	}
+	// expand为false时都替换为第一个词
+	synonyms, _ = ParseSynonyms(strings.NewReader("中国, 中华人民共和国"), base, false)
+	analyzer.TokenFilters[0] = NewSynonymFilter(synonyms)
+	if got := positions(analyzer.Analyze("中华人民共和国和中国")); got != "中国@0:1 和@1:1 中国@2:1" {
+		t.Errorf("bad tokens %s", got)
+	}
+}
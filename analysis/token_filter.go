@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"io/fs"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+// TokenFilter 处理分词后的token, 可以删除、修改或增加token
+type TokenFilter interface {
+	Filter(tokens []*Token) []*Token
+}
+
+// TokenFilterFunc 把函数作为TokenFilter
+type TokenFilterFunc func(tokens []*Token) []*Token
+
+func (f TokenFilterFunc) Filter(tokens []*Token) []*Token {
+	return f(tokens)
+}
+
+// NewKeepFilter 保留keep返回true的token, 删除的token的位置增量累加到下一个保留的token上
+func NewKeepFilter(keep func(token *Token) bool) TokenFilter {
+	return TokenFilterFunc(func(tokens []*Token) []*Token {
+		kept := tokens[:0]
+		increment := 0
+		for _, token := range tokens {
+			if !keep(token) {
+				increment += token.PositionIncrement
+				continue
+			}
+			token.PositionIncrement += increment
+			increment = 0
+			kept = append(kept, token)
+		}
+		return kept
+	})
+}
+
+// LoadStopWords 加载停用词, 每行一个词, 如dict目录下的idf_stop_words.txt(jieba.IdfStopWordsName)
+func LoadStopWords(fsys fs.FS, name string) (map[string]struct{}, error) {
+	return LoadStopWordsWithOptions(fsys, name, nil)
+}
+
+// LoadStopWordsWithOptions 同LoadStopWords, 通过opts选择严格或宽松模式, 见dictload.Options
+func LoadStopWordsWithOptions(fsys fs.FS, name string, opts *dictload.Options) (map[string]struct{}, error) {
+	words := map[string]struct{}{}
+	_, err := dictload.Load(fsys, name, opts, func(line string) error {
+		word := strings.TrimSpace(line)
+		if word == "" {
+			return dictload.ErrSkip
+		}
+		if _, ok := words[word]; ok {
+			return dictload.ErrDuplicate
+		}
+		words[word] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// NewStopFilter 删除停用词
+func NewStopFilter(words map[string]struct{}) TokenFilter {
+	return NewKeepFilter(func(token *Token) bool {
+		_, ok := words[token.Term]
+		return !ok
+	})
+}
+
+// LowercaseFilter 把Term转为小写, 分词时关闭了小写(SegOptions.KeepCase)时使用
+var LowercaseFilter TokenFilter = TokenFilterFunc(func(tokens []*Token) []*Token {
+	for _, token := range tokens {
+		token.Term = strings.ToLower(token.Term)
+	}
+	return tokens
+})
+
+// NewLengthFilter 保留Term的字符数在[minLen, maxLen]之间的token, maxLen <= 0 表示不限制最大长度
+func NewLengthFilter(minLen, maxLen int) TokenFilter {
+	return NewKeepFilter(func(token *Token) bool {
+		l := utf8.RuneCountInString(token.Term)
+		return l >= minLen && (maxLen <= 0 || l <= maxLen)
+	})
+}
+
+// PunctuationFilter 删除只包含标点、符号和空白的token
+var PunctuationFilter = NewKeepFilter(func(token *Token) bool {
+	for _, r := range token.Term {
+		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) && !unicode.IsSpace(r) {
+			return true
+		}
+	}
+	return false
+})
+
+// NewTypeFilter allow为true时只保留types中类型的token, 否则删除这些类型的token, 普通词的类型为TypeWord
+func NewTypeFilter(allow bool, types ...string) TokenFilter {
+	set := toSet(types)
+	return NewKeepFilter(func(token *Token) bool {
+		_, ok := set[token.Type]
+		return ok == allow
+	})
+}
+
+// NewPosFilter allow为true时只保留词性在pos中的token, 否则删除这些词性的token
+func NewPosFilter(allow bool, pos ...string) TokenFilter {
+	set := toSet(pos)
+	return NewKeepFilter(func(token *Token) bool {
+		_, ok := set[token.Pos]
+		return ok == allow
+	})
+}
+
+func toSet(items []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		set[item] = struct{}{}
+	}
+	return set
+}
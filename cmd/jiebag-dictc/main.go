@@ -0,0 +1,34 @@
+// jiebag-dictc 把dict目录编译成预编译词库文件, 用dictbin.Open加载
+//
+//	jiebag-dictc -dict ./dict -out jiebag.dict
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/rolandhe/jiebag/dictbin"
+)
+
+func main() {
+	dictRoot := flag.String("dict", "dict", "dict directory")
+	out := flag.String("out", "jiebag.dict", "output file")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	if err = dictbin.Compile(os.DirFS(*dictRoot), w); err != nil {
+		log.Fatal(err)
+	}
+	if err = w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+// Package dict 把默认词库通过embed打包进二进制文件, 不需要再单独部署dict目录.
+// 只有引用了本包的程序才会包含词库数据.
+package dict
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/rolandhe/jiebag/jieba"
+	"github.com/rolandhe/jiebag/pinyin"
+)
+
+//go:embed *.txt user pinyin pos
+var FS embed.FS
+
+func NewSegmentHandler() (*jieba.SegmentHandler, error) {
+	return jieba.NewSegmentHandlerFS(FS)
+}
+
+func NewTfidf(segHandler *jieba.SegmentHandler) (jieba.Tfidf, error) {
+	return jieba.NewTfidfFS(FS, segHandler)
+}
+
+func LoadNerModel() (*jieba.NerModel, error) {
+	return jieba.LoadNerModel(FS, jieba.NerModelName)
+}
+
+func PinyinFS() fs.FS {
+	sub, err := fs.Sub(FS, "pinyin")
+	if err != nil {
+		panic(err.Error())
+	}
+	return sub
+}
+
+func LoadPinyin() (*pinyin.DictNode, error) {
+	return pinyin.LoadDictFS(PinyinFS())
+}
+
+func LoadPinyinGuess() (*pinyin.PureGuessNode, error) {
+	return pinyin.LoadGuessFS(PinyinFS())
+}
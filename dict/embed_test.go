@@ -0,0 +1,112 @@
+package dict
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/rolandhe/jiebag/jieba"
+	"github.com/rolandhe/jiebag/pinyin"
+)
+
+func TestNewSegmentHandler(t *testing.T) {
+	handler, err := NewSegmentHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var words []string
+	for _, token := range handler.SegParagraph("我来到北京清华大学", jieba.ModeSearch) {
+		words = append(words, token.Word)
+	}
+	fmt.Println(words)
+	if got := strings.Join(words, "/"); got != "我/来到/北京/清华大学" {
+		t.Errorf("bad cut %s", got)
+	}
+
+	// 未登录词由hmm切分, 再由pos目录中的词性模型标注
+	tokens := handler.SegParagraph("程序员祝海林和朱会震", jieba.ModeSearch)
+	fmt.Println(tokens)
+	if last := tokens[len(tokens)-1]; last.Word != "朱会震" || last.Pos != "nr" {
+		t.Errorf("bad pos %s/%s", last.Word, last.Pos)
+	}
+}
+
+// hidePos 隐藏pos目录, 同没有词性模型的词典目录
+type hidePos struct {
+	fs.FS
+}
+
+func (h hidePos) Open(name string) (fs.File, error) {
+	if name == jieba.PosModelDirName || strings.HasPrefix(name, jieba.PosModelDirName+"/") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return h.FS.Open(name)
+}
+
+func TestPosModelKeepsBoundaries(t *testing.T) {
+	handler, err := NewSegmentHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := jieba.NewSegmentHandlerFS(hidePos{FS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 词性模型只标注词性, 切分同没有词性模型时
+	for _, sentence := range []string{
+		"这是一个伸手不见五指的黑夜",
+		"一词多义",
+		"这条路很长",
+		"敞帚自珍",
+		"产品质量责任法草案",
+		"程序员祝海林和朱会震是在孙健的左面和右面, 范凯在最右面.再往左是李松洪",
+		"韩玉赏鉴来到北京, 99元",
+	} {
+		tokens := handler.SegParagraph(sentence, jieba.ModeSearch)
+		fmt.Println(tokens)
+		var got, expect []string
+		for _, token := range tokens {
+			got = append(got, token.Word)
+			if token.Pos == "" {
+				t.Errorf("%s has no pos", token.Word)
+			}
+		}
+		for _, token := range plain.SegParagraph(sentence, jieba.ModeSearch) {
+			expect = append(expect, token.Word)
+		}
+		if strings.Join(got, "/") != strings.Join(expect, "/") {
+			t.Errorf("expect %v, got %v", expect, got)
+		}
+	}
+}
+
+func TestNewTfidf(t *testing.T) {
+	handler, err := NewSegmentHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tf, err := NewTfidf(handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keywords := tf.TopNByString("太阳照在桑干河上，太阳每天升起，当太阳落山后，月亮升了起来，桑干河静静地流淌着", 3)
+	fmt.Println(keywords)
+	if len(keywords) != 3 || keywords[0].Word != "桑干河" {
+		t.Errorf("bad keywords %v", keywords)
+	}
+}
+
+func TestLoadPinyin(t *testing.T) {
+	node, err := LoadPinyin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(node.ConvertString("河北乐亭", pinyin.WithoutTone))
+}
+
+func TestLoadNerModel(t *testing.T) {
+	if _, err := LoadNerModel(); err != nil {
+		t.Fatal(err)
+	}
+}
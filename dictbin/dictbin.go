@@ -0,0 +1,127 @@
+// Package dictbin 预编译词库: 把分词词典、hmm模型和拼音词典编译成一个带版本和校验的二进制文件.
+// Open使用mmap只读映射文件, 分词词典直接使用映射的数据, 启动时不需要解析文本词典,
+// 多个进程打开同一个文件时共享物理内存.
+package dictbin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/rolandhe/jiebag/internal/binfmt"
+	"github.com/rolandhe/jiebag/internal/mmap"
+	"github.com/rolandhe/jiebag/jieba"
+	"github.com/rolandhe/jiebag/pinyin"
+)
+
+const (
+	magic = "JIEBAGDB"
+	// Version 文件格式版本, 分词部分另有jieba.CompiledVersion
+	Version = 2
+
+	secSegment = "segment"
+	secPinyin  = "pinyin"
+)
+
+// Compile 编译fsys中的词库写入w, fsys的根目录对应dict目录, 存在pinyin子目录时同时编译拼音词典
+func Compile(fsys fs.FS, w io.Writer) error {
+	handler, err := jieba.NewSegmentHandlerFS(fsys)
+	if err != nil {
+		return err
+	}
+	segData, err := handler.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	writer := binfmt.NewWriter(magic, Version)
+	writer.Add(secSegment, segData)
+
+	if _, err = fs.Stat(fsys, "pinyin"); err == nil {
+		pinyinFS, err := fs.Sub(fsys, "pinyin")
+		if err != nil {
+			return err
+		}
+		node, err := pinyin.LoadDictFS(pinyinFS)
+		if err != nil {
+			return err
+		}
+		pinyinData, err := node.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		writer.Add(secPinyin, pinyinData)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	_, err = writer.WriteTo(w)
+	return err
+}
+
+type Dict struct {
+	handler *jieba.SegmentHandler
+	pinyin  *pinyin.DictNode
+	closer  func() error
+}
+
+// Open mmap打开预编译的词库文件, 不再使用时调用Close, Close之后不能再使用SegmentHandler
+func Open(fp string) (*Dict, error) {
+	data, closer, err := mmap.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	d, err := Load(data)
+	if err != nil {
+		_ = closer()
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+	d.closer = closer
+	return d, nil
+}
+
+// Load 从内存加载预编译的词库, 分词词典直接引用data, 使用期间data不能被修改
+func Load(data []byte) (*Dict, error) {
+	f, err := binfmt.Parse(data, magic)
+	if err != nil {
+		return nil, err
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("dict file version %d, expect %d", f.Version, Version)
+	}
+
+	segData, err := f.MustSection(secSegment)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dict{}
+	if d.handler, err = jieba.NewSegmentHandlerFromBinary(segData); err != nil {
+		return nil, err
+	}
+	if pinyinData, ok := f.Section(secPinyin); ok {
+		d.pinyin = &pinyin.DictNode{}
+		if err = d.pinyin.UnmarshalBinary(pinyinData); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *Dict) SegmentHandler() *jieba.SegmentHandler {
+	return d.handler
+}
+
+// Pinyin 拼音词典, 编译时没有pinyin目录时返回nil
+func (d *Dict) Pinyin() *pinyin.DictNode {
+	return d.pinyin
+}
+
+func (d *Dict) Close() error {
+	if d.closer == nil {
+		return nil
+	}
+	closer := d.closer
+	d.closer = nil
+	return closer()
+}
@@ -0,0 +1,95 @@
+package dictbin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/jieba"
+	"github.com/rolandhe/jiebag/pinyin"
+)
+
+func testFS(t *testing.T) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	files := map[string]string{
+		"dict.txt":             "../test/jieba/dict.txt",
+		"user/user.dict":       "../test/jieba/user/user.dict",
+		"prob_emit.txt":        "../dict/prob_emit.txt",
+		"pinyin/pinyin.txt":    "../test/pinyin/pinyin.txt",
+		"pinyin/polyphone.txt": "../test/pinyin/polyphone.txt",
+	}
+	for name, fp := range files {
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	return fsys
+}
+
+func TestCompileAndOpen(t *testing.T) {
+	fsys := testFS(t)
+	fp := filepath.Join(t.TempDir(), "jiebag.dict")
+	f, err := os.Create(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Compile(fsys, f); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	handler, err := jieba.NewSegmentHandlerFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentence := "我来到北京清华大学，中华人民共和国"
+	expect := fmt.Sprint(handler.SegParagraph(sentence, jieba.ModeIndex))
+	if got := fmt.Sprint(d.SegmentHandler().SegParagraph(sentence, jieba.ModeIndex)); got != expect {
+		t.Errorf("expect %s, got %s", expect, got)
+	}
+
+	if d.Pinyin() == nil {
+		t.Fatal("pinyin not compiled")
+	}
+	pys := d.Pinyin().ConvertString("藏南", pinyin.ToneTail)
+	if fmt.Sprint(pys) != "[zang4 nan2]" {
+		t.Errorf("bad pinyin %v", pys)
+	}
+}
+
+func BenchmarkOpen(b *testing.B) {
+	rootDict, _ := filepath.Abs("../dict")
+	if _, err := os.Stat(filepath.Join(rootDict, jieba.BaseDictName)); err != nil {
+		b.Skip(err)
+	}
+	fp := filepath.Join(b.TempDir(), "jiebag.dict")
+	f, err := os.Create(fp)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = Compile(os.DirFS(rootDict), f); err != nil {
+		b.Fatal(err)
+	}
+	_ = f.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d, err := Open(fp)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = d.Close()
+	}
+}
@@ -0,0 +1,133 @@
+// Package dictload 词库文件的统一加载: 逐行解析, 严格模式下遇到格式错误的行时返回带文件名、行号和原因的错误,
+// 宽松模式下跳过错误的行. 每个文件的加载结果(行数、条目数、重复和跳过的行)通过slog.Logger输出
+package dictload
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+)
+
+// maxLineSize 单行的最大长度
+const maxLineSize = 1024 * 1024
+
+var (
+	// ErrSkip 由解析函数返回, 表示该行不是词条, 如空行、注释
+	ErrSkip = errors.New("skip line")
+	// ErrDuplicate 由解析函数返回, 表示词条重复, 只计数不报错
+	ErrDuplicate = errors.New("duplicate")
+)
+
+// Options 加载选项, nil表示严格模式并使用slog.Default()
+type Options struct {
+	// Lenient 宽松模式, 跳过格式错误的行, 默认遇到错误的行时返回错误
+	Lenient bool
+	// Logger 输出加载结果和跳过的行, 默认为slog.Default()
+	Logger *slog.Logger
+}
+
+func (opts *Options) lenient() bool {
+	return opts != nil && opts.Lenient
+}
+
+func (opts *Options) logger() *slog.Logger {
+	if opts == nil || opts.Logger == nil {
+		return slog.Default()
+	}
+	return opts.Logger
+}
+
+// LineError 文件中格式错误的行
+type LineError struct {
+	File   string
+	Line   int
+	Text   string
+	Reason error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Reason)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Reason
+}
+
+// Report 一个文件的加载结果
+type Report struct {
+	File string
+	// Lines 读取的行数, Entries 加载的词条数, Duplicates 重复的词条数
+	Lines      int
+	Entries    int
+	Duplicates int
+	// Skipped 宽松模式下跳过的格式错误的行
+	Skipped []*LineError
+}
+
+func (r *Report) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("file", r.File),
+		slog.Int("lines", r.Lines),
+		slog.Int("entries", r.Entries),
+		slog.Int("duplicates", r.Duplicates),
+		slog.Int("skipped", len(r.Skipped)),
+	)
+}
+
+// Load 逐行读取fsys中的name, 每行调用parse, 第一行的BOM会被去掉.
+// parse返回ErrSkip时忽略该行, 返回ErrDuplicate时计为重复, 返回其他错误时严格模式下返回LineError, 宽松模式下跳过该行
+func Load(fsys fs.FS, name string, opts *Options, parse func(line string) error) (*Report, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadReader(f, name, opts, parse)
+}
+
+// LoadReader 同Load, 从r读取, name是错误和加载结果中的文件名
+func LoadReader(r io.Reader, name string, opts *Options, parse func(line string) error) (*Report, error) {
+	var err error
+	logger := opts.logger()
+	report := &Report{File: name}
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scan.Scan() {
+		report.Lines++
+		line := scan.Text()
+		if report.Lines == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		err = parse(line)
+		switch {
+		case err == nil:
+			report.Entries++
+		case errors.Is(err, ErrSkip):
+		case errors.Is(err, ErrDuplicate):
+			report.Duplicates++
+			logger.Debug("duplicate dict line", "file", name, "line", report.Lines, "text", line)
+		default:
+			lineErr := &LineError{File: name, Line: report.Lines, Text: line, Reason: err}
+			if !opts.lenient() {
+				return nil, lineErr
+			}
+			report.Skipped = append(report.Skipped, lineErr)
+			logger.Warn("skip bad dict line", "file", name, "line", report.Lines, "reason", err.Error())
+		}
+	}
+	if err = scan.Err(); err != nil {
+		return nil, fmt.Errorf("%s:%d: %w", name, report.Lines+1, err)
+	}
+
+	level := slog.LevelDebug
+	if report.Duplicates > 0 || len(report.Skipped) > 0 {
+		level = slog.LevelInfo
+	}
+	logger.Log(context.Background(), level, "dict loaded", "report", report)
+	return report, nil
+}
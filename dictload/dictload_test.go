@@ -0,0 +1,77 @@
+package dictload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func parseKV(m map[string]string) func(line string) error {
+	return func(line string) error {
+		if line == "" || strings.HasPrefix(line, "#") {
+			return ErrSkip
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return errors.New("missing =")
+		}
+		if _, exist := m[k]; exist {
+			return ErrDuplicate
+		}
+		m[k] = v
+		return nil
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt": &fstest.MapFile{Data: []byte("\ufeffa=1\n# comment\n\nbad\nb=2\na=3\n")},
+	}
+
+	// 严格模式返回第一个错误的行
+	_, err := Load(fsys, "a.txt", nil, parseKV(map[string]string{}))
+	fmt.Println(err)
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.File != "a.txt" || lineErr.Line != 4 || lineErr.Text != "bad" {
+		t.Fatalf("bad line error %v", err)
+	}
+	if err.Error() != "a.txt:4: missing =" {
+		t.Errorf("bad error message %s", err)
+	}
+
+	var buf bytes.Buffer
+	opts := &Options{
+		Lenient: true,
+		Logger:  slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	m := map[string]string{}
+	report, err := Load(fsys, "a.txt", opts, parseKV(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(buf.String())
+	if report.Lines != 6 || report.Entries != 2 || report.Duplicates != 1 || len(report.Skipped) != 1 || report.Skipped[0].Line != 4 {
+		t.Errorf("bad report %+v", report)
+	}
+	// 第一行的BOM被去掉, 重复的词保留第一个
+	if m["a"] != "1" || m["b"] != "2" {
+		t.Errorf("bad entries %v", m)
+	}
+	for _, expect := range []string{
+		`level=WARN msg="skip bad dict line" file=a.txt line=4 reason="missing ="`,
+		`level=DEBUG msg="duplicate dict line" file=a.txt line=6`,
+		`level=INFO msg="dict loaded" report.file=a.txt report.lines=6 report.entries=2 report.duplicates=1 report.skipped=1`,
+	} {
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("missing log %s", expect)
+		}
+	}
+
+	if _, err = Load(fsys, "none.txt", nil, parseKV(map[string]string{})); err == nil {
+		t.Error("expect open error")
+	}
+}
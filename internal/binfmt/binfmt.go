@@ -0,0 +1,165 @@
+// Package binfmt 预编译词库使用的二进制容器格式.
+//
+// 文件布局(小端):
+//
+//	magic[8] version(uint32) sectionCount(uint32)
+//	sectionCount个section头: name[24] offset(uint64) length(uint64) crc(uint32) 0(uint32)
+//	headerCrc(uint32) 0(uint32)
+//	section数据, 每个section的offset按8字节对齐
+//
+// crc均为crc32 castagnoli, headerCrc覆盖它之前的所有字节. section数据按8字节对齐,
+// 因此可以直接把mmap的数据当作[]uint32、[]float64使用, 不需要拷贝.
+package binfmt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	magicLen       = 8
+	nameLen        = 24
+	fixedHeaderLen = magicLen + 8
+	sectionHeadLen = nameLen + 8 + 8 + 4 + 4
+	align          = 8
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var ErrBadFormat = errors.New("binfmt: bad format")
+
+type section struct {
+	name string
+	data []byte
+}
+
+type Writer struct {
+	magic    string
+	version  uint32
+	sections []section
+}
+
+func NewWriter(magic string, version uint32) *Writer {
+	return &Writer{
+		magic:   magic,
+		version: version,
+	}
+}
+
+func (w *Writer) Add(name string, data []byte) {
+	w.sections = append(w.sections, section{name: name, data: data})
+}
+
+func alignUp(n int) int {
+	return (n + align - 1) / align * align
+}
+
+func (w *Writer) Bytes() ([]byte, error) {
+	if len(w.magic) != magicLen {
+		return nil, fmt.Errorf("binfmt: magic must be %d bytes", magicLen)
+	}
+	headerLen := fixedHeaderLen + len(w.sections)*sectionHeadLen + 8
+	size := alignUp(headerLen)
+	offsets := make([]int, len(w.sections))
+	for i, sec := range w.sections {
+		if len(sec.name) > nameLen {
+			return nil, fmt.Errorf("binfmt: section name %s too long", sec.name)
+		}
+		offsets[i] = size
+		size = alignUp(size + len(sec.data))
+	}
+
+	buf := make([]byte, size)
+	copy(buf, w.magic)
+	le := binary.LittleEndian
+	le.PutUint32(buf[magicLen:], w.version)
+	le.PutUint32(buf[magicLen+4:], uint32(len(w.sections)))
+	p := fixedHeaderLen
+	for i, sec := range w.sections {
+		copy(buf[p:p+nameLen], sec.name)
+		le.PutUint64(buf[p+nameLen:], uint64(offsets[i]))
+		le.PutUint64(buf[p+nameLen+8:], uint64(len(sec.data)))
+		le.PutUint32(buf[p+nameLen+16:], crc32.Checksum(sec.data, crcTable))
+		p += sectionHeadLen
+		copy(buf[offsets[i]:], sec.data)
+	}
+	le.PutUint32(buf[p:], crc32.Checksum(buf[:p], crcTable))
+	return buf, nil
+}
+
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	buf, err := w.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := out.Write(buf)
+	return int64(n), err
+}
+
+// File 解析后的容器, section数据引用原始的data, 不做拷贝
+type File struct {
+	Version  uint32
+	sections map[string][]byte
+}
+
+// Parse 解析并校验data, magic不一致或者crc校验失败时返回错误
+func Parse(data []byte, magic string) (*File, error) {
+	if len(data) < fixedHeaderLen || string(data[:magicLen]) != magic {
+		return nil, ErrBadFormat
+	}
+	le := binary.LittleEndian
+	f := &File{
+		Version:  le.Uint32(data[magicLen:]),
+		sections: map[string][]byte{},
+	}
+	count := int(le.Uint32(data[magicLen+4:]))
+	p := fixedHeaderLen
+	if count < 0 || len(data) < p+count*sectionHeadLen+8 {
+		return nil, ErrBadFormat
+	}
+	if crc32.Checksum(data[:p+count*sectionHeadLen], crcTable) != le.Uint32(data[p+count*sectionHeadLen:]) {
+		return nil, fmt.Errorf("%w: header checksum mismatch", ErrBadFormat)
+	}
+	for i := 0; i < count; i++ {
+		name := string(trimZero(data[p : p+nameLen]))
+		offset := le.Uint64(data[p+nameLen:])
+		length := le.Uint64(data[p+nameLen+8:])
+		crc := le.Uint32(data[p+nameLen+16:])
+		p += sectionHeadLen
+		if offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return nil, fmt.Errorf("%w: section %s out of range", ErrBadFormat, name)
+		}
+		sec := data[offset : offset+length : offset+length]
+		if crc32.Checksum(sec, crcTable) != crc {
+			return nil, fmt.Errorf("%w: section %s checksum mismatch", ErrBadFormat, name)
+		}
+		f.sections[name] = sec
+	}
+	return f, nil
+}
+
+func trimZero(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
+
+func (f *File) Section(name string) ([]byte, bool) {
+	sec, ok := f.sections[name]
+	return sec, ok
+}
+
+// MustSection 返回section, 不存在时返回ErrBadFormat
+func (f *File) MustSection(name string) ([]byte, error) {
+	sec, ok := f.sections[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: section %s not found", ErrBadFormat, name)
+	}
+	return sec, nil
+}
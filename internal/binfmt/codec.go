@@ -0,0 +1,88 @@
+package binfmt
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Encoder 顺序写入的简单编码, 用于加载时需要重建的结构
+type Encoder struct {
+	buf []byte
+}
+
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) PutUvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *Encoder) PutFloat64(v float64) {
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *Encoder) PutString(s string) {
+	e.PutUvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Decoder 与Encoder对应, 出错后后续读取都返回零值, 最后通过Err检查
+type Decoder struct {
+	buf []byte
+	err error
+}
+
+func NewDecoder(buf []byte) *Decoder {
+	return &Decoder{buf: buf}
+}
+
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) Uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = ErrBadFormat
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// Len 读取一个长度, 长度超过剩余数据时认为数据损坏, 防止分配过大的内存
+func (d *Decoder) Len() int {
+	v := d.Uvarint()
+	if v > uint64(len(d.buf)) {
+		d.err = ErrBadFormat
+		return 0
+	}
+	return int(v)
+}
+
+func (d *Decoder) Float64() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = ErrBadFormat
+		return 0
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *Decoder) String() string {
+	l := d.Len()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.buf[:l])
+	d.buf = d.buf[l:]
+	return s
+}
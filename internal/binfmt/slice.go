@@ -0,0 +1,88 @@
+package binfmt
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+)
+
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+func aligned(b []byte, n int) bool {
+	return len(b) > 0 && uintptr(unsafe.Pointer(&b[0]))%uintptr(n) == 0
+}
+
+// Uint32s 把小端数据转换成[]uint32, 小端机器且对齐时直接引用b, 否则拷贝
+func Uint32s(b []byte) []uint32 {
+	n := len(b) / 4
+	if n == 0 {
+		return nil
+	}
+	if littleEndian && aligned(b, 4) {
+		return unsafe.Slice((*uint32)(unsafe.Pointer(&b[0])), n)
+	}
+	ret := make([]uint32, n)
+	for i := range ret {
+		ret[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	return ret
+}
+
+// Float64s 同Uint32s
+func Float64s(b []byte) []float64 {
+	n := len(b) / 8
+	if n == 0 {
+		return nil
+	}
+	if littleEndian && aligned(b, 8) {
+		return unsafe.Slice((*float64)(unsafe.Pointer(&b[0])), n)
+	}
+	ret := make([]float64, n)
+	for i := range ret {
+		ret[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return ret
+}
+
+func Uint32Bytes(values []uint32) []byte {
+	buf := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], v)
+	}
+	return buf
+}
+
+func Float64Bytes(values []float64) []byte {
+	buf := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(buf[i*8:], math.Float64bits(v))
+	}
+	return buf
+}
+
+// Int32s 同Uint32s
+func Int32s(b []byte) []int32 {
+	n := len(b) / 4
+	if n == 0 {
+		return nil
+	}
+	if littleEndian && aligned(b, 4) {
+		return unsafe.Slice((*int32)(unsafe.Pointer(&b[0])), n)
+	}
+	ret := make([]int32, n)
+	for i := range ret {
+		ret[i] = int32(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return ret
+}
+
+func Int32Bytes(values []int32) []byte {
+	buf := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], uint32(v))
+	}
+	return buf
+}
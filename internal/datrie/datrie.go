@@ -0,0 +1,371 @@
+// Package datrie 双数组trie.
+//
+// 状态s经过字符c转移到t = base[s] + code(c), 当且仅当check[t] == s时转移有效.
+// 字符先映射成稠密的code, 出现次数多的字符code小, 使数组更紧凑.
+// 所有数组都是定长整数, 可以直接使用mmap的数据.
+package datrie
+
+import (
+	"errors"
+	"slices"
+)
+
+const (
+	// directCodes 小于该值的rune直接查表, 其他rune在extRunes中二分查找
+	directCodes = 0x10000
+	free        = -1
+)
+
+var ErrBadFormat = errors.New("datrie: bad format")
+
+type DoubleArray struct {
+	Base  []int32
+	Check []int32
+	// Value 状态对应的key序号, -1表示不是key
+	Value []int32
+	// Codes rune到code的映射, 0表示不在字母表中
+	Codes []uint32
+	// ExtRunes/ExtCodes 超出Codes范围的rune, 按rune有序
+	ExtRunes []uint32
+	ExtCodes []uint32
+}
+
+func (da *DoubleArray) code(r rune) uint32 {
+	if r >= 0 && int(r) < len(da.Codes) {
+		return da.Codes[r]
+	}
+	lo, hi := 0, len(da.ExtRunes)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		v := da.ExtRunes[mid]
+		if v == uint32(r) {
+			return da.ExtCodes[mid]
+		}
+		if v < uint32(r) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return 0
+}
+
+// Child 从状态state经过r转移, 根状态是0
+func (da *DoubleArray) Child(state int32, r rune) (int32, bool) {
+	c := da.code(r)
+	if c == 0 {
+		return 0, false
+	}
+	t := int(da.Base[state]) + int(c)
+	if t >= len(da.Check) || da.Check[t] != state {
+		return 0, false
+	}
+	return int32(t), true
+}
+
+// Value 状态对应的key序号
+func (da *DoubleArray) ValueOf(state int32) (int32, bool) {
+	v := da.Value[state]
+	return v, v >= 0
+}
+
+// Find 精确查找key
+func (da *DoubleArray) Find(key string) (int32, bool) {
+	if len(key) == 0 {
+		return 0, false
+	}
+	var state int32
+	var ok bool
+	for _, r := range key {
+		if state, ok = da.Child(state, r); !ok {
+			return 0, false
+		}
+	}
+	return da.ValueOf(state)
+}
+
+// Validate 检查从外部加载的数组, 保证查找不会越界, keys为key的个数
+func (da *DoubleArray) Validate(keys int) error {
+	n := len(da.Base)
+	if n == 0 || len(da.Check) != n || len(da.Value) != n || len(da.ExtRunes) != len(da.ExtCodes) {
+		return ErrBadFormat
+	}
+	for i := 0; i < n; i++ {
+		if da.Base[i] < 0 || da.Check[i] < free || int(da.Check[i]) >= n || da.Value[i] < free || int(da.Value[i]) >= keys {
+			return ErrBadFormat
+		}
+	}
+	return nil
+}
+
+// Keys 还原所有key, 下标即为key的序号, 同Build的输入
+func (da *DoubleArray) Keys() [][]rune {
+	// code到rune的映射
+	var runes []rune
+	setRune := func(c uint32, r rune) {
+		if int(c) >= len(runes) {
+			runes = append(runes, make([]rune, int(c)+1-len(runes))...)
+		}
+		runes[c] = r
+	}
+	for r, c := range da.Codes {
+		if c != 0 {
+			setRune(c, rune(r))
+		}
+	}
+	for i, c := range da.ExtCodes {
+		setRune(c, rune(da.ExtRunes[i]))
+	}
+
+	// 每个状态的子状态, children[start[s]:start[s+1]]
+	n := len(da.Check)
+	start := make([]int32, n+1)
+	for t := 1; t < n; t++ {
+		if s := da.Check[t]; s != free {
+			start[s+1]++
+		}
+	}
+	for s := 0; s < n; s++ {
+		start[s+1] += start[s]
+	}
+	children := make([]int32, start[n])
+	next := slices.Clone(start[:n])
+	count := 0
+	for t := 0; t < n; t++ {
+		if s := da.Check[t]; t > 0 && s != free {
+			children[next[s]] = int32(t)
+			next[s]++
+		}
+		if v := int(da.Value[t]); v >= count {
+			count = v + 1
+		}
+	}
+
+	keys := make([][]rune, count)
+	var key []rune
+	var walk func(s int32)
+	walk = func(s int32) {
+		if v := da.Value[s]; v >= 0 {
+			keys[v] = slices.Clone(key)
+		}
+		for _, t := range children[start[s]:start[s+1]] {
+			key = append(key, runes[t-da.Base[s]])
+			walk(t)
+			key = key[:len(key)-1]
+		}
+	}
+	walk(0)
+	return keys
+}
+
+type builder struct {
+	keys  [][]rune
+	codes map[rune]uint32
+	da    *DoubleArray
+	// nextFree 第一个空闲位置, 查找base时从这里开始
+	nextFree int
+	// skip 并查集, 空闲位置指向自己, 占用的位置指向后一个位置, 查找时跳过连续占用的区间
+	skip []int32
+	// rejects 空闲位置作为第一个子节点的位置失败的次数, 达到maxRejects后查找时跳过, 但仍然可以放其他子节点
+	rejects []uint8
+}
+
+const maxRejects = 16
+
+// Build 从有序且不重复的keys构建, key的序号即为Value
+func Build(keys [][]rune) (*DoubleArray, error) {
+	for i := 1; i < len(keys); i++ {
+		if slices.Compare(keys[i-1], keys[i]) >= 0 {
+			return nil, errors.New("datrie: keys must be sorted and unique")
+		}
+	}
+	b := &builder{
+		keys:     keys,
+		codes:    buildCodes(keys),
+		da:       &DoubleArray{},
+		nextFree: 1,
+	}
+	maxDirect := rune(-1)
+	var ext []rune
+	for r := range b.codes {
+		if r < directCodes {
+			if r > maxDirect {
+				maxDirect = r
+			}
+		} else {
+			ext = append(ext, r)
+		}
+	}
+	b.da.Codes = make([]uint32, maxDirect+1)
+	slices.Sort(ext)
+	for r, c := range b.codes {
+		if r < directCodes {
+			b.da.Codes[r] = c
+		}
+	}
+	for _, r := range ext {
+		b.da.ExtRunes = append(b.da.ExtRunes, uint32(r))
+		b.da.ExtCodes = append(b.da.ExtCodes, b.codes[r])
+	}
+
+	b.resize(1024)
+	b.occupy(0, 0)
+	b.insert(0, 0, 0, len(keys))
+	b.shrink()
+	return b.da, nil
+}
+
+// buildCodes 按字符出现的次数分配code, 次数多的code小
+func buildCodes(keys [][]rune) map[rune]uint32 {
+	count := map[rune]int{}
+	for _, key := range keys {
+		for _, r := range key {
+			count[r]++
+		}
+	}
+	runes := make([]rune, 0, len(count))
+	for r := range count {
+		runes = append(runes, r)
+	}
+	slices.SortFunc(runes, func(a, b rune) int {
+		if count[a] != count[b] {
+			return count[b] - count[a]
+		}
+		return int(a - b)
+	})
+	codes := make(map[rune]uint32, len(runes))
+	for i, r := range runes {
+		codes[r] = uint32(i + 1)
+	}
+	return codes
+}
+
+func (b *builder) resize(n int) {
+	da := b.da
+	old := len(da.Check)
+	if n <= old {
+		return
+	}
+	da.Base = append(da.Base, make([]int32, n-old)...)
+	da.Check = append(da.Check, make([]int32, n-old)...)
+	da.Value = append(da.Value, make([]int32, n-old)...)
+	for i := old; i < n; i++ {
+		da.Check[i] = free
+		da.Value[i] = free
+		b.skip = append(b.skip, int32(i))
+		b.rejects = append(b.rejects, 0)
+	}
+}
+
+func (b *builder) occupy(pos int, state int32) {
+	b.da.Check[pos] = state
+	b.skip[pos] = int32(pos + 1)
+}
+
+// freeFrom pos及之后的第一个空闲位置, 可能等于数组长度
+func (b *builder) freeFrom(pos int) int {
+	for pos < len(b.skip) && int(b.skip[pos]) != pos {
+		next := int(b.skip[pos])
+		if next < len(b.skip) {
+			// 路径减半
+			b.skip[pos] = b.skip[next]
+		}
+		pos = next
+	}
+	return pos
+}
+
+type sibling struct {
+	code   uint32
+	lo, hi int
+}
+
+// insert keys[lo:hi]有相同的前depth个字符, 对应状态state
+func (b *builder) insert(state int32, depth int, lo, hi int) {
+	var siblings []sibling
+	for i := lo; i < hi; i++ {
+		key := b.keys[i]
+		if len(key) == depth {
+			b.da.Value[state] = int32(i)
+			continue
+		}
+		c := b.codes[key[depth]]
+		if n := len(siblings); n > 0 && siblings[n-1].code == c {
+			siblings[n-1].hi = i + 1
+			continue
+		}
+		siblings = append(siblings, sibling{code: c, lo: i, hi: i + 1})
+	}
+	if len(siblings) == 0 {
+		return
+	}
+
+	base := b.findBase(siblings)
+	b.da.Base[state] = int32(base)
+	for _, sib := range siblings {
+		b.occupy(base+int(sib.code), state)
+	}
+	for _, sib := range siblings {
+		b.insert(int32(base+int(sib.code)), depth+1, sib.lo, sib.hi)
+	}
+}
+
+// findBase 查找能放下所有子节点的base, 同darts: 从nextFree开始查找, 已检查区间足够稠密时把nextFree后移,
+// 放弃其中零星的空位, 避免每次都扫描已经几乎占满的区间. 通过skip跳过占用的位置, 多次放不下的空位不再作为起点
+func (b *builder) findBase(siblings []sibling) int {
+	minCode, maxCode := int(siblings[0].code), int(siblings[0].code)
+	for _, sib := range siblings {
+		if c := int(sib.code); c < minCode {
+			minCode = c
+		} else if c > maxCode {
+			maxCode = c
+		}
+	}
+	pos := b.nextFree
+	if pos < minCode {
+		pos = minCode
+	}
+	first := true
+	// tried 检查过的空闲位置数, 其余都是占用的位置
+	tried := 0
+	for pos = b.freeFrom(pos); ; pos = b.freeFrom(pos + 1) {
+		if pos+maxCode-minCode >= len(b.da.Check) {
+			b.resize((pos + maxCode - minCode + 1) * 3 / 2)
+		}
+		if first {
+			b.nextFree = pos
+			first = false
+		}
+		tried++
+		base := pos - minCode
+		ok := true
+		for _, sib := range siblings {
+			if b.da.Check[base+int(sib.code)] != free {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			if b.rejects[pos]++; b.rejects[pos] >= maxRejects {
+				b.skip[pos] = int32(pos + 1)
+			}
+			continue
+		}
+		span := pos - b.nextFree + 1
+		if float64(span-tried)/float64(span) >= 0.95 {
+			b.nextFree = pos
+		}
+		return base
+	}
+}
+
+func (b *builder) shrink() {
+	da := b.da
+	n := len(da.Check)
+	for n > 1 && da.Check[n-1] == free {
+		n--
+	}
+	da.Base = slices.Clip(da.Base[:n])
+	da.Check = slices.Clip(da.Check[:n])
+	da.Value = slices.Clip(da.Value[:n])
+}
@@ -0,0 +1,74 @@
+package datrie
+
+import (
+	"bufio"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	words := []string{"中华", "中华人民共和国", "人民", "人民共和国", "共和", "共和国", "a", "ab", "𠀋𠀌"}
+	var keys [][]rune
+	for _, w := range words {
+		keys = append(keys, []rune(w))
+	}
+	slices.SortFunc(keys, slices.Compare[[]rune])
+
+	da, err := Build(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = da.Validate(len(keys)); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		v, ok := da.Find(string(key))
+		if !ok || int(v) != i {
+			t.Errorf("find %s, got %d %v", string(key), v, ok)
+		}
+	}
+	if got := da.Keys(); !slices.EqualFunc(got, keys, slices.Equal[[]rune]) {
+		t.Errorf("bad keys %q", got)
+	}
+	for _, w := range []string{"中", "中华人民", "b", "共和国家", "𠀋"} {
+		if _, ok := da.Find(w); ok {
+			t.Errorf("%s should not be found", w)
+		}
+	}
+}
+
+func TestBuildSougou(t *testing.T) {
+	f, err := os.Open("../../dict/user/sougou.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	uniq := map[string]struct{}{}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		if items := strings.Fields(scan.Text()); len(items) > 0 {
+			uniq[items[0]] = struct{}{}
+		}
+	}
+	var keys [][]rune
+	for w := range uniq {
+		keys = append(keys, []rune(w))
+	}
+	slices.SortFunc(keys, slices.Compare[[]rune])
+
+	da, err := Build(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		if v, ok := da.Find(string(key)); !ok || int(v) != i {
+			t.Fatalf("find %s, got %d %v", string(key), v, ok)
+		}
+	}
+	if !slices.EqualFunc(da.Keys(), keys, slices.Equal[[]rune]) {
+		t.Error("keys differ from the input")
+	}
+	t.Logf("keys %d, states %d", len(keys), len(da.Base))
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package mmap
+
+import "os"
+
+// Open 不支持mmap的平台直接读入内存
+func Open(fp string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
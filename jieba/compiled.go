@@ -0,0 +1,245 @@
+package jieba
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/rolandhe/jiebag/internal/binfmt"
+)
+
+const (
+	compiledMagic = "JIEBAGSH"
+	// CompiledVersion 预编译词库格式的版本, 格式变化时递增, 加载时版本不一致返回错误
+	CompiledVersion = 2
+
+	secHmmEmit  = "hmm.emit"
+	secPosModel = "pos.model"
+)
+
+// MarshalBinary 把词典、hmm模型和词性模型编译成二进制, 用NewSegmentHandlerFromBinary加载
+func (h *SegmentHandler) MarshalBinary() ([]byte, error) {
+	dt, err := h.compactDict()
+	if err != nil {
+		return nil, err
+	}
+	hmm, ok := h.hmm.(*hmmSegImpl)
+	if !ok {
+		return nil, fmt.Errorf("unsupported hmm type %T", h.hmm)
+	}
+
+	w := binfmt.NewWriter(compiledMagic, CompiledVersion)
+	dt.encode(w)
+	w.Add(secHmmEmit, hmm.encode())
+	if posHmm, ok := h.posHmm.(*posHmmImpl); ok {
+		w.Add(secPosModel, posHmm.encode())
+	}
+	return w.Bytes()
+}
+
+// Compact 返回使用只读双数组trie词典的handler, 与原handler共享hmm模型, AddWord等修改方法返回ErrReadOnlyDict.
+// 从文本词典创建的handler已经使用双数组trie作为基础词典, 修改写入其上的覆盖层, Compact把覆盖层合并进来并去掉覆盖层,
+// 没有修改时直接复用基础词典
+func (h *SegmentHandler) Compact() (*SegmentHandler, error) {
+	if _, ok := h.dict.(*datTrie); ok {
+		return h, nil
+	}
+	dt, err := h.compactDict()
+	if err != nil {
+		return nil, err
+	}
+	return &SegmentHandler{
+		dict:     dt,
+		hmm:      h.hmm,
+		posHmm:   h.posHmm,
+		loadOpts: h.loadOpts,
+	}, nil
+}
+
+// compactDict h的词典对应的双数组trie
+func (h *SegmentHandler) compactDict() (*datTrie, error) {
+	switch dict := h.dict.(type) {
+	case *datTrie:
+		return dict, nil
+	case *overlayTrie:
+		if dt, ok := dict.compactBase(); ok {
+			return dt, nil
+		}
+		return compactTrie(dict)
+	case layer:
+		return compactTrie(dict)
+	}
+	return nil, fmt.Errorf("unsupported dict type %T", h.dict)
+}
+
+// NewSegmentHandlerFromBinary 从MarshalBinary的结果创建handler, 词典直接引用data不做拷贝,
+// 因此handler使用期间data不能被修改或释放, data通常来自mmap, 见jiebag/dictbin包.
+// 预编译的词典是只读的, AddWord等修改方法返回ErrReadOnlyDict.
+func NewSegmentHandlerFromBinary(data []byte) (*SegmentHandler, error) {
+	f, err := binfmt.Parse(data, compiledMagic)
+	if err != nil {
+		return nil, err
+	}
+	if f.Version != CompiledVersion {
+		return nil, fmt.Errorf("compiled dict version %d, expect %d", f.Version, CompiledVersion)
+	}
+
+	trie, err := decodeDatTrie(f)
+	if err != nil {
+		return nil, err
+	}
+
+	sec, err := f.MustSection(secHmmEmit)
+	if err != nil {
+		return nil, err
+	}
+	hmm, err := decodeHmmSeg(sec)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := &SegmentHandler{
+		dict: trie,
+		hmm:  hmm,
+	}
+	if sec, ok := f.Section(secPosModel); ok {
+		if handler.posHmm, err = decodePosHmmSeg(sec); err != nil {
+			return nil, err
+		}
+	}
+	return handler, nil
+}
+
+func encodeRuneFloats(enc *binfmt.Encoder, values map[rune]float64) {
+	keys := make([]rune, 0, len(values))
+	for r := range values {
+		keys = append(keys, r)
+	}
+	slices.Sort(keys)
+	enc.PutUvarint(uint64(len(keys)))
+	for _, r := range keys {
+		enc.PutUvarint(uint64(r))
+		enc.PutFloat64(values[r])
+	}
+}
+
+func decodeRuneFloats(dec *binfmt.Decoder) map[rune]float64 {
+	l := dec.Len()
+	values := make(map[rune]float64, l)
+	for i := 0; i < l && dec.Err() == nil; i++ {
+		r := rune(dec.Uvarint())
+		values[r] = dec.Float64()
+	}
+	return values
+}
+
+func (hmm *hmmSegImpl) encode() []byte {
+	var enc binfmt.Encoder
+	for _, state := range states {
+		encodeRuneFloats(&enc, hmm.emits[state])
+	}
+	return enc.Bytes()
+}
+
+func decodeHmmSeg(data []byte) (HmmSeg, error) {
+	hmm := &hmmSegImpl{
+		emits: map[rune]map[rune]float64{},
+	}
+	dec := binfmt.NewDecoder(data)
+	for _, state := range states {
+		hmm.emits[state] = decodeRuneFloats(dec)
+	}
+	return hmm, dec.Err()
+}
+
+func (hmm *posHmmImpl) encode() []byte {
+	var enc binfmt.Encoder
+	enc.PutUvarint(uint64(len(hmm.states)))
+	for i, ps := range hmm.states {
+		enc.PutUvarint(uint64(ps.bmes))
+		enc.PutString(ps.pos)
+		enc.PutFloat64(hmm.start[i])
+
+		to := make([]int, 0, len(hmm.trans[i]))
+		for y := range hmm.trans[i] {
+			to = append(to, y)
+		}
+		slices.Sort(to)
+		enc.PutUvarint(uint64(len(to)))
+		for _, y := range to {
+			enc.PutUvarint(uint64(y))
+			enc.PutFloat64(hmm.trans[i][y])
+		}
+		encodeRuneFloats(&enc, hmm.emits[i])
+	}
+
+	chars := make([]rune, 0, len(hmm.charState))
+	for r := range hmm.charState {
+		chars = append(chars, r)
+	}
+	slices.Sort(chars)
+	enc.PutUvarint(uint64(len(chars)))
+	for _, r := range chars {
+		enc.PutUvarint(uint64(r))
+		enc.PutUvarint(uint64(len(hmm.charState[r])))
+		for _, id := range hmm.charState[r] {
+			enc.PutUvarint(uint64(id))
+		}
+	}
+	return enc.Bytes()
+}
+
+func decodePosHmmSeg(data []byte) (posHmmSeg, error) {
+	hmm := &posHmmImpl{
+		stateIds:  map[posState]int{},
+		charState: map[rune][]int{},
+	}
+	dec := binfmt.NewDecoder(data)
+	count := dec.Len()
+	for i := 0; i < count && dec.Err() == nil; i++ {
+		ps := posState{bmes: rune(dec.Uvarint()), pos: dec.String()}
+		hmm.states = append(hmm.states, ps)
+		hmm.stateIds[ps] = i
+		hmm.allStates = append(hmm.allStates, i)
+		hmm.start = append(hmm.start, dec.Float64())
+
+		trans := map[int]float64{}
+		l := dec.Len()
+		for j := 0; j < l && dec.Err() == nil; j++ {
+			y := int(dec.Uvarint())
+			trans[y] = dec.Float64()
+		}
+		hmm.trans = append(hmm.trans, trans)
+		hmm.emits = append(hmm.emits, decodeRuneFloats(dec))
+	}
+
+	chars := dec.Len()
+	for i := 0; i < chars && dec.Err() == nil; i++ {
+		r := rune(dec.Uvarint())
+		l := dec.Len()
+		ids := make([]int, 0, l)
+		for j := 0; j < l; j++ {
+			ids = append(ids, int(dec.Uvarint()))
+		}
+		hmm.charState[r] = ids
+	}
+	if err := dec.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, trans := range hmm.trans {
+		for y := range trans {
+			if y >= count {
+				return nil, errors.New("bad pos model state")
+			}
+		}
+	}
+	for _, ids := range hmm.charState {
+		for _, id := range ids {
+			if id >= count {
+				return nil, errors.New("bad pos model state")
+			}
+		}
+	}
+	return hmm, nil
+}
@@ -0,0 +1,42 @@
+package jieba
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCompiledHandler(t *testing.T) {
+	handler := loadTestHandler(t)
+	data, err := handler.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiled, err := NewSegmentHandlerFromBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sentence := range []string{"我来到北京清华大学，王刚说iphone5", "中华人民共和国站起来了", "南京市长江大桥", "小明硕士毕业于中国科学院计算所"} {
+		for _, mode := range []ModeStyle{ModeSearch, ModeIndex, ModeFull} {
+			expect := fmt.Sprint(handler.SegParagraph(sentence, mode))
+			if got := fmt.Sprint(compiled.SegParagraph(sentence, mode)); got != expect {
+				t.Errorf("expect %s, got %s", expect, got)
+			}
+		}
+	}
+	freq, _ := handler.dict.Freq("清华大学")
+	if got, _ := compiled.dict.Freq("清华大学"); fmt.Sprintf("%.3f", got) != fmt.Sprintf("%.3f", freq) {
+		t.Errorf("expect freq %g, got %g", freq, got)
+	}
+	if err = compiled.AddWord("杭研大厦", 10, "nt"); !errors.Is(err, ErrReadOnlyDict) {
+		t.Errorf("expect ErrReadOnlyDict, got %v", err)
+	}
+
+	for i := len(data) / 2; i < len(data)/2+16; i++ {
+		data[i] ^= 0xff
+	}
+	if _, err = NewSegmentHandlerFromBinary(data); err == nil {
+		t.Error("expect checksum error")
+	}
+}
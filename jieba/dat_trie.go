@@ -0,0 +1,202 @@
+package jieba
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/rolandhe/jiebag/internal/binfmt"
+	"github.com/rolandhe/jiebag/internal/datrie"
+)
+
+// datTrie 基于双数组trie的只读词典, 比trieNode的map结构更小更快,
+// 所有数组都是定长整数, 可以直接使用预编译词库mmap的数据
+type datTrie struct {
+	da *datrie.DoubleArray
+	// wordFreq/wordPos 按词的序号存储, 词性为posTable[wordPos[i]]
+	wordFreq []float64
+	wordPos  []uint32
+	posTable []string
+	total    float64
+	minFreq  float64
+}
+
+// compactTrie 把词典l中的所有词构建成双数组trie
+func compactTrie(l layer) (*datTrie, error) {
+	type wordEntry struct {
+		word []rune
+		freq float64
+		pos  string
+	}
+	unlock := l.rlock()
+	var entries []wordEntry
+	l.eachWord(func(word []rune, freq float64, pos string) {
+		entries = append(entries, wordEntry{word: word, freq: freq, pos: pos})
+	})
+	unlock()
+	slices.SortFunc(entries, func(a, b wordEntry) int {
+		return slices.Compare(a.word, b.word)
+	})
+
+	keys := make([][]rune, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.word
+	}
+	da, err := datrie.Build(keys)
+	if err != nil {
+		return nil, err
+	}
+	dt := &datTrie{
+		da:       da,
+		wordFreq: make([]float64, len(keys)),
+		wordPos:  make([]uint32, len(keys)),
+		total:    l.Total(),
+		minFreq:  l.minWeight(),
+	}
+	posIndex := map[string]uint32{}
+	for i, entry := range entries {
+		dt.wordFreq[i] = entry.freq
+		pos, ok := posIndex[entry.pos]
+		if !ok {
+			pos = uint32(len(dt.posTable))
+			dt.posTable = append(dt.posTable, entry.pos)
+			posIndex[entry.pos] = pos
+		}
+		dt.wordPos[i] = pos
+	}
+	return dt, nil
+}
+
+func (dt *datTrie) matchForward(from int, statement []rune) []*segTokenInternal {
+	var ret []*segTokenInternal
+
+	fCreate := func(wl int, freq float64) *segTokenInternal {
+		return &segTokenInternal{
+			Segment: &Segment{
+				Start: from,
+				End:   from + wl,
+			},
+			weight: freq,
+		}
+	}
+
+	var state int32
+	var ok bool
+	for i, v := range statement {
+		if state, ok = dt.da.Child(state, v); !ok {
+			break
+		}
+		if word, isWord := dt.da.ValueOf(state); isWord {
+			ret = append(ret, fCreate(i+1, dt.wordFreq[word]))
+		}
+	}
+	if ret == nil {
+		ret = append(ret, unknownSeg(from, dt.minFreq))
+	}
+	return ret
+}
+
+func (dt *datTrie) Match(sentence []rune) []*Segment {
+	return bestRoute(sentence, dt.matchForward)
+}
+
+func (dt *datTrie) MatchAll(sentence []rune) [][]*Segment {
+	return allMatches(sentence, dt.matchForward)
+}
+
+func (dt *datTrie) ExistShortWord(word string) bool {
+	_, ok := dt.da.Find(word)
+	return ok
+}
+
+func (dt *datTrie) Pos(word string) (string, bool) {
+	i, ok := dt.da.Find(word)
+	if !ok {
+		return "", false
+	}
+	return dt.posTable[dt.wordPos[i]], true
+}
+
+func (dt *datTrie) Freq(word string) (float64, bool) {
+	i, ok := dt.da.Find(word)
+	if !ok {
+		return 0, false
+	}
+	return expFreq(dt.wordFreq[i], dt.total), true
+}
+
+func (dt *datTrie) Total() float64 {
+	return dt.total
+}
+
+const (
+	secDatBase     = "dat.base"
+	secDatCheck    = "dat.check"
+	secDatValue    = "dat.value"
+	secDatCodes    = "dat.codes"
+	secDatExtRunes = "dat.ext_runes"
+	secDatExtCodes = "dat.ext_codes"
+	secDatFreq     = "dat.freq"
+	secDatPos      = "dat.pos"
+	secDatMeta     = "dat.meta"
+)
+
+func (dt *datTrie) encode(w *binfmt.Writer) {
+	w.Add(secDatBase, binfmt.Int32Bytes(dt.da.Base))
+	w.Add(secDatCheck, binfmt.Int32Bytes(dt.da.Check))
+	w.Add(secDatValue, binfmt.Int32Bytes(dt.da.Value))
+	w.Add(secDatCodes, binfmt.Uint32Bytes(dt.da.Codes))
+	w.Add(secDatExtRunes, binfmt.Uint32Bytes(dt.da.ExtRunes))
+	w.Add(secDatExtCodes, binfmt.Uint32Bytes(dt.da.ExtCodes))
+	w.Add(secDatFreq, binfmt.Float64Bytes(dt.wordFreq))
+	w.Add(secDatPos, binfmt.Uint32Bytes(dt.wordPos))
+
+	var enc binfmt.Encoder
+	enc.PutFloat64(dt.total)
+	enc.PutFloat64(dt.minFreq)
+	enc.PutString(strings.Join(dt.posTable, " "))
+	w.Add(secDatMeta, enc.Bytes())
+}
+
+// decodeDatTrie 数组直接引用f中的数据
+func decodeDatTrie(f *binfmt.File) (*datTrie, error) {
+	names := []string{secDatBase, secDatCheck, secDatValue, secDatCodes, secDatExtRunes, secDatExtCodes, secDatFreq, secDatPos, secDatMeta}
+	secs := make([][]byte, len(names))
+	for i, name := range names {
+		var err error
+		if secs[i], err = f.MustSection(name); err != nil {
+			return nil, err
+		}
+	}
+	dt := &datTrie{
+		da: &datrie.DoubleArray{
+			Base:     binfmt.Int32s(secs[0]),
+			Check:    binfmt.Int32s(secs[1]),
+			Value:    binfmt.Int32s(secs[2]),
+			Codes:    binfmt.Uint32s(secs[3]),
+			ExtRunes: binfmt.Uint32s(secs[4]),
+			ExtCodes: binfmt.Uint32s(secs[5]),
+		},
+		wordFreq: binfmt.Float64s(secs[6]),
+		wordPos:  binfmt.Uint32s(secs[7]),
+	}
+	dec := binfmt.NewDecoder(secs[8])
+	dt.total = dec.Float64()
+	dt.minFreq = dec.Float64()
+	dt.posTable = strings.Split(dec.String(), " ")
+	if err := dec.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(dt.wordFreq) != len(dt.wordPos) {
+		return nil, binfmt.ErrBadFormat
+	}
+	if err := dt.da.Validate(len(dt.wordFreq)); err != nil {
+		return nil, err
+	}
+	for _, pos := range dt.wordPos {
+		if int(pos) >= len(dt.posTable) {
+			return nil, binfmt.ErrBadFormat
+		}
+	}
+	return dt, nil
+}
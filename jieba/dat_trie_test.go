@@ -0,0 +1,163 @@
+package jieba
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDatTrie(t *testing.T) {
+	handler := loadTestHandler(t)
+	compact, err := handler.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := compact.dict.(*datTrie); !ok {
+		t.Fatalf("expect datTrie, got %T", compact.dict)
+	}
+	for _, sentence := range sentenceCases {
+		for _, mode := range []ModeStyle{ModeSearch, ModeIndex, ModeFull} {
+			expect := fmt.Sprint(handler.SegParagraph(sentence, mode))
+			if got := fmt.Sprint(compact.SegParagraph(sentence, mode)); got != expect {
+				t.Errorf("expect %s, got %s", expect, got)
+			}
+		}
+	}
+	for _, word := range []string{"清华大学", "中华人民共和国", "小清新", "不存在"} {
+		pos1, ok1 := handler.dict.Pos(word)
+		pos2, ok2 := compact.dict.Pos(word)
+		if pos1 != pos2 || ok1 != ok2 {
+			t.Errorf("%s expect %s %v, got %s %v", word, pos1, ok1, pos2, ok2)
+		}
+	}
+}
+
+func TestDefaultDatTrie(t *testing.T) {
+	handler := loadTestHandler(t)
+	// 文本词典加载后是双数组trie加覆盖层
+	overlay, ok := handler.dict.(*overlayTrie)
+	if !ok {
+		t.Fatalf("expect overlayTrie, got %T", handler.dict)
+	}
+	base, ok := overlay.base.(*datTrie)
+	if !ok {
+		t.Fatalf("expect datTrie base, got %T", overlay.base)
+	}
+	compact, err := handler.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compact.dict != Trie(base) {
+		t.Error("compact without changes should reuse the base dict")
+	}
+
+	if err = handler.AddWord("新词甲乙", 100, "nz"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = handler.DelWord("清华大学"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := base.Freq("新词甲乙"); ok {
+		t.Error("AddWord modifies the base dict")
+	}
+	compact, err = handler.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []*SegmentHandler{handler, compact} {
+		if pos, ok := h.dict.Pos("新词甲乙"); !ok || pos != "nz" {
+			t.Errorf("%T: bad added word %s %v", h.dict, pos, ok)
+		}
+		if h.dict.ExistShortWord("清华大学") {
+			t.Errorf("%T: deleted word exists", h.dict)
+		}
+		f1, _ := h.dict.Freq("清华")
+		f2, _ := base.Freq("清华")
+		if math.Round(f1) != math.Round(f2) {
+			t.Errorf("%T: expect freq %v, got %v", h.dict, f2, f1)
+		}
+	}
+	expect := fmt.Sprint(handler.SegParagraph("我来到北京清华大学的新词甲乙", ModeSearch))
+	fmt.Println(expect)
+	if got := fmt.Sprint(compact.SegParagraph("我来到北京清华大学的新词甲乙", ModeSearch)); got != expect {
+		t.Errorf("expect %s, got %s", expect, got)
+	}
+}
+
+// benchTrie 使用仓库中的sougou.dict作为基础词典, 分别构建map trie和双数组trie
+func benchTrie(b *testing.B) (*trieNodeHolder, *datTrie) {
+	trie, err := newDictTrie(os.DirFS("../dict/user"), "sougou.dict", "", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dt, err := compactTrie(trie)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return trie, dt
+}
+
+func benchSentence() []rune {
+	return []rune(strings.Repeat("阿宝在北京市海淀区的中关村软件园工作，他说人民路上的小吃非常好吃", 20))
+}
+
+func BenchmarkMatchMapTrie(b *testing.B) {
+	trie, _ := benchTrie(b)
+	sentence := benchSentence()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Match(sentence)
+	}
+}
+
+func BenchmarkMatchDatTrie(b *testing.B) {
+	_, dt := benchTrie(b)
+	sentence := benchSentence()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dt.Match(sentence)
+	}
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapInuse
+}
+
+func BenchmarkMemoryMapTrie(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		trie, _ := newDictTrie(os.DirFS("../dict/user"), "sougou.dict", "", nil)
+		b.ReportMetric(float64(heapInUse()-before)/(1<<20), "MB")
+		runtime.KeepAlive(trie)
+	}
+}
+
+func BenchmarkMemoryDatTrie(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		trie, _ := newDictTrie(os.DirFS("../dict/user"), "sougou.dict", "", nil)
+		before := heapInUse()
+		dt, _ := compactTrie(trie)
+		b.ReportMetric(float64(heapInUse()-before)/(1<<20), "MB")
+		runtime.KeepAlive(dt)
+		runtime.KeepAlive(trie)
+	}
+}
+
+// BenchmarkMemoryHandler 使用dict目录下的完整词典创建handler, map trie只在加载时使用
+func BenchmarkMemoryHandler(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		before := heapInUse()
+		handler, err := NewSegmentHandlerFS(os.DirFS("../dict"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(float64(heapInUse()-before)/(1<<20), "MB")
+		runtime.KeepAlive(handler)
+	}
+}
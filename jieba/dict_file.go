@@ -0,0 +1,167 @@
+package jieba
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+// dictHeader 用户词典文件头部的设置, 在第一个词之前, 每行一个 #! key=value:
+//
+//	#! multiplier=10  文件中所有词的词频乘以该值, 包括估计的词频
+//	#! priority=1     同一个词出现在多个词典中时优先级高的生效, 基础词典和默认的优先级为0,
+//	                  优先级相同时后加载的生效, 小于0时只添加其他词典中没有的词
+type dictHeader struct {
+	multiplier float64
+	priority   int
+}
+
+// dictEntry 词典中的一行, freq小于0表示没有给出词频
+type dictEntry struct {
+	word string
+	freq float64
+	pos  string
+}
+
+type dictFile struct {
+	name    string
+	header  dictHeader
+	entries []dictEntry
+}
+
+const dictDirective = "#!"
+
+// parseDictFile 解析词典文件, 每行: 词 [词频] [词性], 词中有空白时用双引号括起来, 如 "edu trust认证" 2000 nz.
+// #开头的行是注释, #开头的词也要用双引号括起来, 如 "#话题" 3. 文件中重复的词只保留第一个. requireFreq为true时没有词频的行是错误的行, 用于基础词典
+func parseDictFile(fsys fs.FS, fp string, requireFreq bool, opts *dictload.Options) (*dictFile, error) {
+	f, err := fsys.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDict(f, fp, requireFreq, opts)
+}
+
+// parseDict 同parseDictFile, 从r读取, name是错误中的文件名
+func parseDict(r io.Reader, name string, requireFreq bool, opts *dictload.Options) (*dictFile, error) {
+	file := &dictFile{name: name, header: dictHeader{multiplier: 1}}
+	preventRepeat := map[string]struct{}{}
+	_, err := dictload.LoadReader(r, name, opts, func(line string) error {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, dictDirective) {
+			if len(file.entries) > 0 {
+				return errors.New("header must be before words")
+			}
+			if err := file.header.parse(strings.TrimSpace(line[len(dictDirective):])); err != nil {
+				return err
+			}
+			return dictload.ErrSkip
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			return dictload.ErrSkip
+		}
+		entry, err := parseDictLine(line)
+		if err != nil {
+			return err
+		}
+		if requireFreq && entry.freq < 0 {
+			return errors.New("missing freq:" + line)
+		}
+		if _, ok := preventRepeat[entry.word]; ok {
+			return dictload.ErrDuplicate
+		}
+		preventRepeat[entry.word] = struct{}{}
+		file.entries = append(file.entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (h *dictHeader) parse(directive string) error {
+	key, value, ok := strings.Cut(directive, "=")
+	if !ok {
+		return errors.New("bad header:" + directive)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	var err error
+	switch key {
+	case "multiplier":
+		h.multiplier, err = strconv.ParseFloat(value, 64)
+		if err == nil && h.multiplier <= 0 {
+			err = errors.New("multiplier must be positive:" + value)
+		}
+	case "priority":
+		h.priority, err = strconv.Atoi(value)
+	default:
+		err = errors.New("unknown header:" + key)
+	}
+	return err
+}
+
+// parseDictLine 解析词典行: 词 [词频] [词性], 同python jieba的用户词典. 数字开头的第二项是词频, 不是合法的数字时是错误的行
+func parseDictLine(line string) (dictEntry, error) {
+	entry := dictEntry{freq: -1}
+	rest := line
+	if strings.HasPrefix(line, `"`) {
+		end := closingQuote(line)
+		if end < 0 {
+			return entry, errors.New("unclosed quote:" + line)
+		}
+		word, err := strconv.Unquote(line[:end+1])
+		if err != nil {
+			return entry, errors.New("bad quoted word:" + line)
+		}
+		entry.word, rest = word, line[end+1:]
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && !unicode.IsSpace(r) {
+			return entry, errors.New("bad quoted word:" + line)
+		}
+	} else {
+		end := strings.IndexFunc(line, unicode.IsSpace)
+		if end < 0 {
+			end = len(line)
+		}
+		entry.word, rest = line[:end], line[end:]
+	}
+	if strings.TrimSpace(entry.word) == "" {
+		return entry, errors.New("empty word:" + line)
+	}
+	entry.word = strings.ToLower(entry.word)
+
+	items := strings.Fields(rest)
+	if len(items) > 0 && isDigit(rune(items[0][0])) {
+		freq, err := strconv.ParseFloat(items[0], 64)
+		if err != nil {
+			return entry, errors.New("bad freq:" + line)
+		}
+		entry.freq, items = freq, items[1:]
+	}
+	if len(items) > 0 {
+		entry.pos, items = items[0], items[1:]
+	}
+	if len(items) > 0 {
+		return entry, errors.New("bad items:" + line)
+	}
+	return entry, nil
+}
+
+// closingQuote 双引号开头的行中结束的双引号的位置, 跳过反斜杠转义的字符
+func closingQuote(line string) int {
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,191 @@
+package jieba
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+func TestParseDictLine(t *testing.T) {
+	cases := []struct {
+		line   string
+		expect string
+	}{
+		{"云计算 5", "云计算 5 "},
+		{"李小福 2 nr", "李小福 2 nr"},
+		{"凱特琳 nz", "凱特琳 -1 nz"},
+		{"台中", "台中 -1 "},
+		{"吖嘛\t3", "吖嘛 3 "},
+		{`"Edu Trust认证" 2000 nz`, "edu trust认证 2000 nz"},
+		{`"a \"b\"" 3`, `a "b" 3 `},
+		{`"#话题" 3`, "#话题 3 "},
+	}
+	for _, c := range cases {
+		entry, err := parseDictLine(c.line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%s %v %s", entry.word, entry.freq, entry.pos); got != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.line, c.expect, got)
+		}
+	}
+	for _, line := range []string{`"unclosed 3`, `"a"b 3`, "word 3 n extra", `"" 3`, "word 3d", "word 3d n"} {
+		if _, err := parseDictLine(line); err == nil {
+			t.Errorf("%s: expect error", line)
+		}
+	}
+}
+
+func TestUserDictHeader(t *testing.T) {
+	base, err := os.ReadFile("../test/jieba/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		BaseDictName:  &fstest.MapFile{Data: base},
+		"user/a.dict": &fstest.MapFile{Data: []byte("#! priority=-1\n长江大桥 1\n新词甲 5\n")},
+		"user/b.dict": &fstest.MapFile{Data: []byte("# 行业词典\n#! multiplier=10\n小清新 3 a\n\"edu trust认证\" 2000\n南京市长\n又拍云 nt\n")},
+		// 文件名在前, 但是优先级高
+		"user/0.dict": &fstest.MapFile{Data: []byte("#!priority=2\n小清新 7\n")},
+	}
+	trie, err := newDictTrie(fsys, BaseDictName, UserDictDirName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	freq := func(word string) float64 {
+		f, ok := trie.Freq(word)
+		if !ok {
+			t.Errorf("%s not found", word)
+		}
+		return math.Round(f)
+	}
+	if f := freq("长江大桥"); f != 800 {
+		t.Errorf("negative priority overrides base dict: %v", f)
+	}
+	if f := freq("新词甲"); f != 5 {
+		t.Errorf("bad freq %v", f)
+	}
+	if f := freq("小清新"); f != 7 {
+		t.Errorf("bad priority freq %v", f)
+	}
+	if f := freq("edu trust认证"); f != 20000 {
+		t.Errorf("bad multiplier freq %v", f)
+	}
+	// 没有词频时估计一个使它不被切开的词频
+	if f := freq("又拍云"); f != 10 {
+		t.Errorf("bad suggested freq %v", f)
+	}
+	if pos, _ := trie.Pos("又拍云"); pos != "nt" {
+		t.Errorf("bad pos %s", pos)
+	}
+	if segments := trie.Match([]rune("南京市长")); len(segments) != 1 {
+		t.Errorf("estimated word is split: %v", len(segments))
+	}
+
+	for name, data := range map[string]string{
+		"header after words": "小清新 3\n#! priority=1\n",
+		"unknown header":     "#! weight=2\n",
+		"bad multiplier":     "#! multiplier=0\n",
+		"bad line":           "小清新 3 a b\n",
+	} {
+		fsys["user/bad.dict"] = &fstest.MapFile{Data: []byte(data)}
+		_, err = newDictTrie(fsys, BaseDictName, UserDictDirName, nil)
+		fmt.Println(err)
+		if err == nil {
+			t.Errorf("%s: expect error", name)
+		}
+	}
+	delete(fsys, "user/bad.dict")
+	fsys[BaseDictName] = &fstest.MapFile{Data: []byte("我 3\n没有词频\n")}
+	if _, err = newDictTrie(fsys, BaseDictName, "", nil); err == nil {
+		t.Error("expect missing freq error")
+	}
+}
+
+func TestLenientLoad(t *testing.T) {
+	base, err := os.ReadFile("../test/jieba/dict.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prob, err := os.ReadFile("../dict/prob_emit.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		BaseDictName:     &fstest.MapFile{Data: base},
+		BaseProbName:     &fstest.MapFile{Data: prob},
+		"user/a.dict":    &fstest.MapFile{Data: []byte("小清新 3 a\n小清新 5\n坏行 3 a b\n坏词频 3d\n\"#话题\" 4\n又拍云 10 nt\n")},
+		"overlay.dict":   &fstest.MapFile{Data: []byte("\"unclosed 3\n租户词 8\n")},
+		IdfDictName:      &fstest.MapFile{Data: []byte("太阳 8.5\n缺少idf\n月亮 x\n河面 9.1\n")},
+		IdfStopWordsName: &fstest.MapFile{Data: []byte("的\n\n了\n")},
+	}
+
+	_, err = NewSegmentHandlerFS(fsys)
+	fmt.Println(err)
+	if err == nil || err.Error() != "user/a.dict:3: bad items:坏行 3 a b" {
+		t.Errorf("bad strict error %v", err)
+	}
+
+	var buf bytes.Buffer
+	opts := &dictload.Options{Lenient: true, Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+	handler, err := NewSegmentHandlerFSWithOptions(fsys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, _ := handler.Freq("小清新"); math.Round(f) != 3 {
+		t.Errorf("duplicate overrides first word: %v", f)
+	}
+	if _, ok := handler.Freq("坏词频"); ok {
+		t.Error("bad freq line loaded")
+	}
+	if _, ok := handler.Freq("#话题"); !ok {
+		t.Error("quoted # word not loaded")
+	}
+	if _, ok := handler.Freq("又拍云"); !ok {
+		t.Error("words after bad line not loaded")
+	}
+	// 覆盖层使用同样的选项
+	overlay, err := handler.LoadOverlay(fsys, "overlay.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := overlay.Freq("租户词"); !ok {
+		t.Error("overlay words not loaded")
+	}
+
+	if _, err = NewTfidfFS(fsys, handler); err == nil {
+		t.Error("expect idf error")
+	}
+	tfidf, err := NewTfidfFSWithOptions(fsys, handler, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := tfidf.(*tfIdfImpl)
+	if len(impl.idfMap) != 2 || impl.idfMap["太阳"] != 8.5 || impl.idfMap["河面"] != 9.1 {
+		t.Errorf("bad idf map %v", impl.idfMap)
+	}
+	if _, ok := impl.stopWords[""]; ok || len(impl.stopWords) != 2 {
+		t.Errorf("bad stop words %v", impl.stopWords)
+	}
+
+	fmt.Print(buf.String())
+	for _, expect := range []string{
+		`"file":"user/a.dict","line":3,"reason":"bad items:坏行 3 a b"`,
+		`"report":{"file":"user/a.dict","lines":6,"entries":3,"duplicates":1,"skipped":2}`,
+		`"file":"user/a.dict","line":4,"reason":"bad freq:坏词频 3d"`,
+		`"file":"overlay.dict","line":1`,
+		`"file":"idf_dict.txt","line":2,"reason":"bad idf items:缺少idf"`,
+		`"file":"idf_dict.txt","line":3`,
+	} {
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("missing log %s", expect)
+		}
+	}
+}
@@ -1,15 +1,13 @@
 package jieba
 
 import (
-	"bufio"
-	"errors"
 	"io/fs"
-	"log"
 	"math"
-	"os"
-	"path/filepath"
-	"strconv"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/rolandhe/jiebag/dictload"
 )
 
 type Segment struct {
@@ -20,36 +18,58 @@ type Segment struct {
 type segTokenInternal struct {
 	*Segment
 	weight float64
+	// unknown 该位置没有词典词时的单字
+	unknown bool
 }
 
-func newDictTrie(baseDict string, userDictDir string) (Trie, error) {
+// unknownSeg 没有词典词的位置使用权重为minFreq的单字
+func unknownSeg(from int, minFreq float64) *segTokenInternal {
+	return &segTokenInternal{Segment: &Segment{Start: from, End: from + 1}, weight: minFreq, unknown: true}
+}
+
+// newDict 加载词典后构建成只读的双数组trie, 作为覆盖层的基础词典, AddWord等运行时的修改只写入覆盖层
+func newDict(fsys fs.FS, opts *dictload.Options) (Trie, error) {
+	trie, err := newDictTrie(fsys, BaseDictName, UserDictDirName, opts)
+	if err != nil {
+		return nil, err
+	}
+	dt, err := compactTrie(trie)
+	if err != nil {
+		return nil, err
+	}
+	return newOverlayTrie(dt), nil
+}
+
+func newDictTrie(fsys fs.FS, baseDict string, userDictDir string, opts *dictload.Options) (*trieNodeHolder, error) {
 
 	root := &trieNodeHolder{
 		trieNode:  &trieNode{},
 		minFreq:   math.MaxFloat64,
-		shortWord: map[string]struct{}{},
+		shortWord: map[string]*trieNode{},
 	}
 
-	if err := loadBase(root, baseDict); err != nil {
+	if err := loadBase(root, fsys, baseDict, opts); err != nil {
 		return nil, err
 	}
 
-	if err := loadByDir(root, userDictDir, func(nd *trieNode) {
-		nd.freq = math.Log(nd.freq / root.total)
-	}); err != nil {
+	if err := loadByDir(root, fsys, userDictDir, opts); err != nil {
 		return nil, err
 	}
 	return root, nil
 }
 
-func loadBase(root *trieNodeHolder, baseDict string) error {
-	var collect []*trieNode
-	if err := root.loadDict(baseDict, func(nd *trieNode) {
-		root.total += nd.freq
-		collect = append(collect, nd)
-	}); err != nil {
+func loadBase(root *trieNodeHolder, fsys fs.FS, baseDict string, opts *dictload.Options) error {
+	file, err := parseDictFile(fsys, baseDict, true, opts)
+	if err != nil {
 		return err
 	}
+	var collect []*trieNode
+	for _, entry := range file.entries {
+		root.addWord([]rune(entry.word), entry.freq*file.header.multiplier, entry.pos, func(nd *trieNode) {
+			root.total += nd.freq
+			collect = append(collect, nd)
+		})
+	}
 	for _, nd := range collect {
 		nd.freq = math.Log(nd.freq / root.total)
 		root.minFreq = math.Min(nd.freq, root.minFreq)
@@ -57,22 +77,82 @@ func loadBase(root *trieNodeHolder, baseDict string) error {
 	return nil
 }
 
-func loadByDir(root *trieNodeHolder, dirPath string, afterWord func(nd *trieNode)) error {
+// loadByDir 加载目录下的用户词典, 用户词典的词不计入total.
+// 优先级大于等于0的词典按优先级从低到高加载并覆盖已有的词, 小于0的从高到低加载, 只添加没有的词, 见dictHeader
+func loadByDir(root *trieNodeHolder, fsys fs.FS, dirPath string, opts *dictload.Options) error {
 	if len(dirPath) == 0 {
 		return nil
 	}
 
-	fileSystem := os.DirFS(dirPath)
-
-	return fs.WalkDir(fileSystem, ".", func(path string, d fs.DirEntry, err error) error {
+	var files []*dictFile
+	err := fs.WalkDir(fsys, dirPath, func(fp string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
 			return nil
 		}
-		return root.loadDict(filepath.Join(dirPath, path), afterWord)
+		file, err := parseDictFile(fsys, fp, false, opts)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		pi, pj := files[i].header.priority, files[j].header.priority
+		if (pi < 0) != (pj < 0) {
+			return pj < 0
+		}
+		if pi < 0 {
+			return pi > pj
+		}
+		return pi < pj
 	})
+
+	toLog := func(nd *trieNode) {
+		nd.freq = math.Log(nd.freq / root.total)
+	}
+	for _, file := range files {
+		for _, entry := range file.entries {
+			existing := root.shortWord[entry.word]
+			if existing != nil && file.header.priority < 0 {
+				continue
+			}
+			word := []rune(entry.word)
+			freq := entry.freq
+			if freq < 0 {
+				freq = root.suggestFreq(word)
+			}
+			pos := entry.pos
+			if pos == "" && existing != nil {
+				pos = existing.pos
+			}
+			root.addWord(word, freq*file.header.multiplier, pos, toLog)
+		}
+	}
+	return nil
+}
+
+// suggestFreq 同SegmentHandler.SuggestFreq, 不使用hmm时使word不被切开的最小词频, 只在加载词典时调用, 不加锁
+func (root *trieNodeHolder) suggestFreq(word []rune) float64 {
+	freq := 1.0
+	for _, seg := range bestRoute(word, root.matchForward) {
+		freq *= root.rawFreq(string(word[seg.Start:seg.End]), 1) / root.total
+	}
+	return math.Max(math.Floor(freq*root.total)+1, root.rawFreq(string(word), 1))
+}
+
+// rawFreq 词的词频(非对数), 词不存在时返回def
+func (root *trieNodeHolder) rawFreq(word string, def float64) float64 {
+	nd, ok := root.shortWord[word]
+	if !ok {
+		return def
+	}
+	return expFreq(nd.freq, root.total)
 }
 
 func (seg *Segment) ToString(sentence []rune) string {
@@ -84,7 +164,22 @@ func (seg *Segment) len() int {
 
 type Trie interface {
 	Match(sentence []rune) []*Segment
+	// MatchAll 返回每个位置开始的所有词典词(DAG), 没有词的位置返回单字
+	MatchAll(sentence []rune) [][]*Segment
 	ExistShortWord(word string) bool
+	// Pos 返回词典中词的词性, 词典中未标注词性时返回空串
+	Pos(word string) (string, bool)
+	// Freq 返回词典中词的词频(非对数)
+	Freq(word string) (float64, bool)
+	Total() float64
+}
+
+// MutableTrie 运行时可以修改的词典, 修改与查询可以并发进行
+type MutableTrie interface {
+	Trie
+	// AddWord 添加或更新词, freq为词频(非对数)
+	AddWord(word string, freq float64, pos string)
+	DelWord(word string) bool
 }
 
 type trieNode struct {
@@ -92,13 +187,15 @@ type trieNode struct {
 
 	wordEnd bool
 	freq    float64
+	pos     string
 }
 
 type trieNodeHolder struct {
 	*trieNode
 	total     float64
 	minFreq   float64
-	shortWord map[string]struct{}
+	shortWord map[string]*trieNode
+	lock      sync.RWMutex
 }
 
 func (node *trieNode) hasNext() bool {
@@ -107,19 +204,26 @@ func (node *trieNode) hasNext() bool {
 }
 
 func (root *trieNodeHolder) Match(sentence []rune) []*Segment {
+	root.lock.RLock()
+	defer root.lock.RUnlock()
+	return bestRoute(sentence, root.matchForward)
+}
+
+func (root *trieNodeHolder) MatchAll(sentence []rune) [][]*Segment {
+	root.lock.RLock()
+	defer root.lock.RUnlock()
+	return allMatches(sentence, root.matchForward)
+}
+
+type matchForwardFunc func(from int, statement []rune) []*segTokenInternal
+
+// bestRoute 根据每个位置的候选词计算概率最大的切分路径
+func bestRoute(sentence []rune, matchForward matchForwardFunc) []*Segment {
 	l := len(sentence)
 	if l == 0 {
 		return nil
 	}
-	matchSegTokens := make([][]*segTokenInternal, l)
-	for i := 0; i < l; i++ {
-		matchSegTokens[i] = root.matchForward(i, sentence[i:])
-	}
-	stat := make([]*segTokenInternal, l)
-
-	for i := l - 1; i >= 0; i-- {
-		calc(stat, matchSegTokens[i], i)
-	}
+	_, stat := routeStat(sentence, matchForward)
 
 	last := stat[0]
 	var result []*Segment
@@ -134,6 +238,35 @@ func (root *trieNodeHolder) Match(sentence []rune) []*Segment {
 	return result
 }
 
+// routeStat 每个位置的候选词, 以及calc计算的从每个位置到句尾的最优路径的第一个词和路径的权重
+func routeStat(sentence []rune, matchForward matchForwardFunc) ([][]*segTokenInternal, []*segTokenInternal) {
+	l := len(sentence)
+	matchSegTokens := make([][]*segTokenInternal, l)
+	for i := 0; i < l; i++ {
+		matchSegTokens[i] = matchForward(i, sentence[i:])
+	}
+	stat := make([]*segTokenInternal, l)
+
+	for i := l - 1; i >= 0; i-- {
+		calc(stat, matchSegTokens[i], i)
+	}
+	return matchSegTokens, stat
+}
+
+func allMatches(sentence []rune, matchForward matchForwardFunc) [][]*Segment {
+	l := len(sentence)
+	if l == 0 {
+		return nil
+	}
+	dag := make([][]*Segment, l)
+	for i := 0; i < l; i++ {
+		for _, seg := range matchForward(i, sentence[i:]) {
+			dag[i] = append(dag[i], seg.Segment)
+		}
+	}
+	return dag
+}
+
 func calc(stat []*segTokenInternal, thisSegTokens []*segTokenInternal, pos int) {
 	l := len(stat)
 	var maxWeight = math.Inf(-1)
@@ -188,41 +321,13 @@ func (root *trieNodeHolder) matchForward(from int, statement []rune) []*segToken
 		p = found
 	}
 	if ret == nil {
-		ret = append(ret, fCreate(1, root.minFreq))
+		ret = append(ret, unknownSeg(from, root.minFreq))
 	}
 
 	return ret
 }
 
-func (root *trieNodeHolder) loadDict(fp string, afterWord func(nd *trieNode)) error {
-	f, err := os.Open(fp)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	preventRepeat := map[string]struct{}{}
-
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		line := scan.Text()
-		word, freq, err := splitDictLine(line)
-		if err != nil {
-			return err
-		}
-
-		if _, ok := preventRepeat[word]; ok {
-			log.Printf("%s is repeat in %s\n", line, fp)
-			continue
-		}
-		preventRepeat[word] = struct{}{}
-		root.addWord([]rune(word), freq, afterWord)
-	}
-
-	return scan.Err()
-}
-
-func (root *trieNodeHolder) addWord(runes []rune, freq float64, afterWord func(nd *trieNode)) {
+func (root *trieNodeHolder) addWord(runes []rune, freq float64, pos string, afterWord func(nd *trieNode)) {
 	l := len(runes)
 	if l == 0 {
 		return
@@ -241,26 +346,67 @@ func (root *trieNodeHolder) addWord(runes []rune, freq float64, afterWord func(n
 		if isEnd {
 			curNode.wordEnd = true
 			curNode.freq = freq
+			curNode.pos = pos
 			if afterWord != nil {
 				afterWord(curNode)
 			}
+			root.shortWord[string(runes)] = curNode
 		}
 		p = curNode
 	}
-	root.shortWord[string(runes)] = struct{}{}
 }
 
 func (root *trieNodeHolder) ExistShortWord(word string) bool {
+	root.lock.RLock()
+	defer root.lock.RUnlock()
 	_, ok := root.shortWord[word]
 	return ok
 }
 
-func splitDictLine(line string) (string, float64, error) {
-	items := strings.Fields(line)
-	if len(items) < 2 {
-		return "", 0.0, errors.New("bad items:" + line)
+func (root *trieNodeHolder) Pos(word string) (string, bool) {
+	root.lock.RLock()
+	defer root.lock.RUnlock()
+	nd, ok := root.shortWord[word]
+	if !ok {
+		return "", false
+	}
+	return nd.pos, true
+}
+
+func (root *trieNodeHolder) Freq(word string) (float64, bool) {
+	root.lock.RLock()
+	defer root.lock.RUnlock()
+	if _, ok := root.shortWord[word]; !ok {
+		return 0, false
+	}
+	return root.rawFreq(word, 0), true
+}
+
+// expFreq 把词典中存储的对数概率还原为词频
+func expFreq(logFreq float64, total float64) float64 {
+	return math.Exp(logFreq) * total
+}
+
+func (root *trieNodeHolder) Total() float64 {
+	return root.total
+}
+
+// AddWord 与用户词典一样, 新词不计入total, 不影响已有词的权重
+func (root *trieNodeHolder) AddWord(word string, freq float64, pos string) {
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	root.addWord([]rune(strings.ToLower(word)), math.Log(freq/root.total), pos, nil)
+}
+
+func (root *trieNodeHolder) DelWord(word string) bool {
+	root.lock.Lock()
+	defer root.lock.Unlock()
+	word = strings.ToLower(word)
+	nd, ok := root.shortWord[word]
+	if !ok {
+		return false
 	}
-	freq, err := strconv.ParseFloat(items[1], 64)
-	word := strings.ToLower(items[0])
-	return word, freq, err
+	nd.wordEnd = false
+	delete(root.shortWord, word)
+	return true
 }
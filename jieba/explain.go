@@ -0,0 +1,211 @@
+package jieba
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explanation 分词的中间结果, 用于排查切分错误, 可以通过String输出文本或通过json.Marshal输出json
+type Explanation struct {
+	Text      string                 `json:"text"`
+	Sentences []*SentenceExplanation `json:"sentences"`
+}
+
+// SentenceExplanation 一个句子的分词过程, 除Start外的位置都是句子中的rune偏移
+type SentenceExplanation struct {
+	Text string `json:"text"`
+	// Start 句子在原文中的rune偏移
+	Start int `json:"start"`
+	// Dag 每个位置开始的候选词, Weight是对数词频, 没有词典词的位置是Unknown的单字
+	Dag [][]*Candidate `json:"dag"`
+	// Routes calc计算的从每个位置到句尾的最优路径, 是该路径的第一个词, Weight是整个路径的对数概率
+	Routes []*Candidate `json:"routes"`
+	// Path 词典的最优路径
+	Path []string `json:"path"`
+	// Hmm 交给hmm切分的片段
+	Hmm []*HmmSpan `json:"hmm"`
+	// Words 最终的切分结果
+	Words []string `json:"words"`
+}
+
+// Candidate 句子中[Start,End)的词
+type Candidate struct {
+	Word    string  `json:"word"`
+	Start   int     `json:"start"`
+	End     int     `json:"end"`
+	Weight  float64 `json:"weight"`
+	Unknown bool    `json:"unknown,omitempty"`
+}
+
+// HmmSpan 由viterbi切分的连续汉字, States是每个字的BMES状态, 有词性模型时为 状态/词性, 如B/nr,
+// Probs是每个字上BMES状态的累计对数概率
+type HmmSpan struct {
+	Text   string    `json:"text"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	States []string  `json:"states"`
+	Probs  []float64 `json:"probs"`
+}
+
+// Explain 按SegParagraph的默认选项(使用hmm)对s分词, 返回每个句子的候选词DAG、最优路径的权重、交给hmm的片段和viterbi的状态序列
+func (h *SegmentHandler) Explain(s string) (*Explanation, error) {
+	dict, ok := h.dict.(layer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported dict type %T", h.dict)
+	}
+	opts := &SegOptions{}
+	paragraph := []rune(s)
+	for i, r := range paragraph {
+		paragraph[i] = regularize(r, opts)
+	}
+
+	e := &Explanation{Text: s}
+	var st sentenceTrace
+	for i, r := range paragraph {
+		if couldTrieSegSupport(r) {
+			st.to++
+			continue
+		}
+		if st.length() > 0 {
+			e.Sentences = append(e.Sentences, h.explainSentence(dict, paragraph[st.from:st.to], st.from))
+		}
+		st = sentenceTrace{from: i + 1, to: i + 1}
+	}
+	if st.length() > 0 {
+		e.Sentences = append(e.Sentences, h.explainSentence(dict, paragraph[st.from:st.to], st.from))
+	}
+	return e, nil
+}
+
+func (h *SegmentHandler) explainSentence(dict layer, sentence []rune, start int) *SentenceExplanation {
+	se := &SentenceExplanation{Text: string(sentence), Start: start}
+	newCandidate := func(seg *segTokenInternal) *Candidate {
+		return &Candidate{
+			Word:    string(sentence[seg.Start:seg.End]),
+			Start:   seg.Start,
+			End:     seg.End,
+			Weight:  seg.weight,
+			Unknown: seg.unknown,
+		}
+	}
+
+	unlock := dict.rlock()
+	matches, stat := routeStat(sentence, dict.matchForward)
+	unlock()
+	for i, segs := range matches {
+		candidates := make([]*Candidate, len(segs))
+		for j, seg := range segs {
+			candidates[j] = newCandidate(seg)
+		}
+		se.Dag = append(se.Dag, candidates)
+		se.Routes = append(se.Routes, newCandidate(stat[i]))
+	}
+
+	// 同segSentence, 连续的单字不是词典词时交给hmm
+	var st sentenceTrace
+	for pos := 0; pos < len(sentence); {
+		seg := stat[pos]
+		se.Path = append(se.Path, string(sentence[seg.Start:seg.End]))
+		if seg.len() == 1 {
+			st.to = seg.End
+		} else {
+			se.explainHmm(h, sentence, st)
+			st = sentenceTrace{from: seg.End, to: seg.End}
+		}
+		pos = seg.End
+	}
+	se.explainHmm(h, sentence, st)
+
+	for _, token := range h.segSentence(sentence, &SegOptions{}) {
+		se.Words = append(se.Words, token.word)
+	}
+	return se
+}
+
+// explainHmm 记录sentence[st.from:st.to]中每段连续汉字的viterbi状态, 同HmmSeg.Cut
+func (se *SentenceExplanation) explainHmm(h *SegmentHandler, sentence []rune, st sentenceTrace) {
+	if st.length() == 0 || h.dict.ExistShortWord(string(sentence[st.from:st.to])) {
+		return
+	}
+	from := -1
+	for i := st.from; i <= st.to; i++ {
+		if i < st.to && isCjk(sentence[i]) {
+			if from < 0 {
+				from = i
+			}
+			continue
+		}
+		if from >= 0 {
+			if span := h.hmmSpan(sentence[from:i], from); span != nil {
+				se.Hmm = append(se.Hmm, span)
+			}
+			from = -1
+		}
+	}
+}
+
+// hmmSpan 同cutHmm, 有词性模型时在切分的状态上标注词性, 模型不是内置的实现时返回nil
+func (h *SegmentHandler) hmmSpan(chinese []rune, start int) *HmmSpan {
+	hmm, ok := h.hmm.(*hmmSegImpl)
+	if !ok {
+		return nil
+	}
+	span := &HmmSpan{Text: string(chinese), Start: start, End: start + len(chinese)}
+	var route []rune
+	route, span.Probs = hmm.route(chinese)
+	for _, state := range route {
+		span.States = append(span.States, string(state))
+	}
+	if h.posHmm == nil {
+		return span
+	}
+	posHmm, ok := h.posHmm.(*posHmmImpl)
+	if !ok {
+		return nil
+	}
+	if posRoute, _ := posHmm.route(chinese, route); posRoute != nil {
+		for i, ps := range posRoute {
+			span.States[i] += "/" + ps.pos
+		}
+	}
+	return span
+}
+
+// String 文本格式, 便于贴到问题报告中
+func (e *Explanation) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "text: %s\n", e.Text)
+	for _, se := range e.Sentences {
+		se.writeTo(&sb)
+	}
+	return sb.String()
+}
+
+func (se *SentenceExplanation) writeTo(sb *strings.Builder) {
+	fmt.Fprintf(sb, "sentence %d: %s\n", se.Start, se.Text)
+	sb.WriteString("  dag:\n")
+	for i, candidates := range se.Dag {
+		fmt.Fprintf(sb, "    %d:", i)
+		for _, c := range candidates {
+			fmt.Fprintf(sb, " %s(%.4g", c.Word, c.Weight)
+			if c.Unknown {
+				sb.WriteString(" unknown")
+			}
+			sb.WriteString(")")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("  routes:\n")
+	for i, c := range se.Routes {
+		fmt.Fprintf(sb, "    %d: %s %.4g\n", i, c.Word, c.Weight)
+	}
+	fmt.Fprintf(sb, "  path: %s\n", strings.Join(se.Path, "/"))
+	for _, span := range se.Hmm {
+		fmt.Fprintf(sb, "  hmm %d-%d %s:", span.Start, span.End, span.Text)
+		for i, state := range span.States {
+			fmt.Fprintf(sb, " %s(%.4g)", state, span.Probs[i])
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(sb, "  words: %s\n", strings.Join(se.Words, "/"))
+}
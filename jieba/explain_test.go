@@ -0,0 +1,112 @@
+package jieba
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	handler := loadTestHandler(t)
+	e, err := handler.Explain("我来到北京清华大学")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(e)
+	se := e.Sentences[0]
+	var dag []string
+	for _, c := range se.Dag[5] {
+		dag = append(dag, c.Word)
+	}
+	if got := strings.Join(dag, "/"); got != "清华/清华大学" {
+		t.Errorf("bad dag %s", got)
+	}
+	if c := se.Dag[2][0]; c.Word != "到" || !c.Unknown {
+		t.Errorf("expect unknown single char %+v", c)
+	}
+	// 最优路径的权重是路径上的词的对数词频之和
+	sum := 0.0
+	for pos := 0; pos < len(se.Routes); pos = se.Routes[pos].End {
+		c := se.Routes[pos]
+		for _, d := range se.Dag[pos] {
+			if d.End == c.End {
+				sum += d.Weight
+			}
+		}
+	}
+	if math.Abs(sum-se.Routes[0].Weight) > 1e-9 {
+		t.Errorf("expect route weight %v, got %v", sum, se.Routes[0].Weight)
+	}
+	if got := strings.Join(se.Path, "/"); got != "我/来到/北京/清华大学" {
+		t.Errorf("bad path %s", got)
+	}
+	if len(se.Hmm) != 0 {
+		t.Errorf("unexpected hmm spans %v", se.Hmm)
+	}
+
+	s := "韩玉赏鉴来到北京, abc"
+	e, err = handler.Explain(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(e)
+	if len(e.Sentences) != 2 || e.Sentences[1].Start != 10 || e.Sentences[1].Text != "abc" {
+		t.Fatalf("bad sentences %v", e.Sentences)
+	}
+	span := e.Sentences[0].Hmm[0]
+	if span.Text != "韩玉赏鉴" || span.Start != 0 || span.End != 4 || len(span.States) != 4 || len(span.Probs) != 4 {
+		t.Errorf("bad hmm span %+v", span)
+	}
+	var words []string
+	for _, se := range e.Sentences {
+		words = append(words, se.Words...)
+	}
+	var expect []string
+	for _, word := range tokenWords(handler.SegParagraph(s, ModeSearch)) {
+		if strings.TrimSpace(word) != "" && word != "," {
+			expect = append(expect, word)
+		}
+	}
+	if strings.Join(words, "/") != strings.Join(expect, "/") {
+		t.Errorf("expect words %v, got %v", expect, words)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Explanation
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Sentences[0].Hmm[0].States[0] != span.States[0] {
+		t.Errorf("bad json %s", data)
+	}
+
+	// 没有词性模型时是BMES状态
+	plain := &SegmentHandler{dict: handler.dict, hmm: handler.hmm}
+	e, err = plain.Explain("韩玉赏鉴")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(e)
+	for _, state := range e.Sentences[0].Hmm[0].States {
+		if !strings.Contains("BMES", state) || len(state) != 1 {
+			t.Errorf("bad state %s", state)
+		}
+	}
+
+	compact, err := handler.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err = compact.Explain("我来到北京清华大学")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(e.Sentences[0].Path, "/"); got != "我/来到/北京/清华大学" {
+		t.Errorf("bad compact path %s", got)
+	}
+}
@@ -1,11 +1,13 @@
 package jieba
 
 import (
-	"bufio"
-	"os"
+	"errors"
+	"io/fs"
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/rolandhe/jiebag/dictload"
 )
 
 const minFloat = -3.14e100
@@ -51,12 +53,12 @@ type HmmSeg interface {
 	Cut(statement []rune) []string
 }
 
-func newHmmSeg(dictPath string) (HmmSeg, error) {
+func newHmmSeg(fsys fs.FS, dictPath string, opts *dictload.Options) (HmmSeg, error) {
 	hmm := &hmmSegImpl{
 		emits: map[rune]map[rune]float64{},
 	}
 
-	if err := hmm.loadModel(dictPath); err != nil {
+	if err := hmm.loadModel(fsys, dictPath, opts); err != nil {
 		return nil, err
 	}
 
@@ -67,31 +69,33 @@ type hmmSegImpl struct {
 	emits map[rune]map[rune]float64
 }
 
-func (hmm *hmmSegImpl) loadModel(fp string) error {
-	f, err := os.Open(fp)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	scan := bufio.NewScanner(f)
+// loadModel 每个状态一行状态名, 之后每行: 字 概率
+func (hmm *hmmSegImpl) loadModel(fsys fs.FS, fp string, opts *dictload.Options) error {
 	var values map[rune]float64
-	for scan.Scan() {
-		line := scan.Text()
+	_, err := dictload.Load(fsys, fp, opts, func(line string) error {
 		items := strings.Fields(line)
-
-		if len(items) == 1 {
+		switch {
+		case len(items) == 0:
+			return dictload.ErrSkip
+		case len(items) == 1:
 			values = map[rune]float64{}
 			rvs := []rune(items[0])
 			hmm.emits[rvs[0]] = values
-		} else {
-			rvs := []rune(items[0])
-			if values[rvs[0]], err = strconv.ParseFloat(items[1], 64); err != nil {
-				return err
-			}
+			return dictload.ErrSkip
+		case len(items) != 2:
+			return errors.New("bad emit items:" + line)
+		case values == nil:
+			return errors.New("emit before state:" + line)
 		}
-	}
-
-	return scan.Err()
+		p, err := strconv.ParseFloat(items[1], 64)
+		if err != nil {
+			return err
+		}
+		rvs := []rune(items[0])
+		values[rvs[0]] = p
+		return nil
+	})
+	return err
 }
 
 func resetChinese(chinese []rune) []rune {
@@ -109,7 +113,7 @@ func (hmm *hmmSegImpl) Cut(statement []rune) []string {
 	var other strings.Builder
 
 	for _, r := range statement {
-		if isCjkNormal(r) {
+		if isCjk(r) {
 			if other.Len() > 0 {
 				tokens = hmm.processOtherUnknownWords(other.String(), tokens)
 				other.Reset()
@@ -144,6 +148,33 @@ type candidate struct {
 }
 
 func (hmm *hmmSegImpl) viterbi(chinese []rune, tokens []string) []string {
+	l := len(chinese)
+	postList, _ := hmm.route(chinese)
+
+	begin := 0
+	next := 0
+	for i := 0; i < l; i++ {
+		postR := postList[i]
+		if postR == 'B' {
+			begin = i
+		} else if postR == 'E' {
+			next = i + 1
+			tokens = append(tokens, string(chinese[begin:next]))
+		} else if postR == 'S' {
+			next = i + 1
+			tokens = append(tokens, string(chinese[i:next]))
+		}
+	}
+
+	if next < l {
+		tokens = append(tokens, string(chinese[next:]))
+	}
+
+	return tokens
+}
+
+// route viterbi求出的最优BMES状态序列, 以及每个位置上该状态的累计对数概率
+func (hmm *hmmSegImpl) route(chinese []rune) ([]rune, []float64) {
 	l := len(chinese)
 	v := make([]map[rune]float64, l)
 	for i := 0; i < l; i++ {
@@ -210,30 +241,18 @@ func (hmm *hmmSegImpl) viterbi(chinese []rune, tokens []string) []string {
 		win = win.p
 	}
 	slices.Reverse(postList)
-
-	begin := 0
-	next := 0
-	for i := 0; i < l; i++ {
-		postR := postList[i]
-		if postR == 'B' {
-			begin = i
-		} else if postR == 'E' {
-			next = i + 1
-			tokens = append(tokens, string(chinese[begin:next]))
-		} else if postR == 'S' {
-			next = i + 1
-			tokens = append(tokens, string(chinese[i:next]))
-		}
+	probs := make([]float64, l)
+	for i, state := range postList {
+		probs[i] = v[i][state]
 	}
-
-	if next < l {
-		tokens = append(tokens, string(chinese[next:]))
-	}
-
-	return tokens
+	return postList, probs
 }
 
 func (hmm *hmmSegImpl) processOtherUnknownWords(other string, tokens []string) []string {
+	return splitOtherUnknownWords(other, tokens)
+}
+
+func splitOtherUnknownWords(other string, tokens []string) []string {
 	tokenIndexes := reSkip.FindAllStringIndex(other, -1)
 	offset := 0
 
@@ -2,7 +2,7 @@ package jieba
 
 import (
 	"fmt"
-	"path"
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -12,7 +12,7 @@ func TestSegHmm(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	hmm, err := newHmmSeg(path.Join(rootDict, BaseProbName))
+	hmm, err := newHmmSeg(os.DirFS(rootDict), BaseProbName, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
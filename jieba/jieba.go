@@ -1,8 +1,15 @@
 package jieba
 
 import (
+	"errors"
 	"fmt"
-	"path"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rolandhe/jiebag/dictload"
 )
 
 type ModeStyle int
@@ -10,6 +17,9 @@ type ModeStyle int
 const ModeSearch ModeStyle = 0
 const ModeIndex ModeStyle = 1
 
+// ModeFull 全模式, 输出句子中所有可能的词典词, 同python jieba的cut_all=True
+const ModeFull ModeStyle = 2
+
 const (
 	BaseDictName    = "dict.txt"
 	UserDictDirName = "user"
@@ -17,29 +27,94 @@ const (
 )
 
 func MewSegmentHandler(dictRootPath string) (*SegmentHandler, error) {
-	trie, err := newDictTrie(path.Join(dictRootPath, BaseDictName), path.Join(dictRootPath, UserDictDirName))
+	return NewSegmentHandlerFS(os.DirFS(dictRootPath))
+}
+
+// NewSegmentHandlerFS 从fsys加载词库, fsys的根目录对应dict目录, 可以是embed.FS, 见jiebag/dict包
+func NewSegmentHandlerFS(fsys fs.FS) (*SegmentHandler, error) {
+	return NewSegmentHandlerFSWithOptions(fsys, nil)
+}
+
+// NewSegmentHandlerFSWithOptions 同NewSegmentHandlerFS, 通过opts选择严格或宽松模式, 之后的LoadOverlay使用同样的选项
+func NewSegmentHandlerFSWithOptions(fsys fs.FS, opts *dictload.Options) (*SegmentHandler, error) {
+	trie, err := newDict(fsys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	hmm, err := newHmmSeg(fsys, BaseProbName, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	hmm, err := newHmmSeg(path.Join(dictRootPath, BaseProbName))
+	posHmm, err := newPosHmmSeg(fsys, PosModelDirName, opts)
 	if err != nil {
 		return nil, err
 	}
 	return &SegmentHandler{
-		dict: trie,
-		hmm:  hmm,
+		dict:     trie,
+		hmm:      hmm,
+		posHmm:   posHmm,
+		loadOpts: opts,
 	}, nil
 }
 
 type SegToken struct {
-	Word  string
+	// Word 规范化(小写、半角)后的词, 用于索引
+	Word string
+	// Text 原始输入中的文本, 用于展示和高亮
+	Text string
+	// Start/End rune偏移
 	Start int
 	End   int
+	// Pos 词性, 与python jieba posseg的标注集一致, 如 n, v, nr, ns, eng, m, x
+	Pos string
+	// ByteStart/ByteEnd 在原始输入中的字节偏移, 可以直接用于go的字符串切片
+	ByteStart int
+	ByteEnd   int
+	// Utf16Start/Utf16End 在原始输入中的utf16偏移, 用于javascript、java等使用utf16的环境
+	Utf16Start int
+	Utf16End   int
+	// Type 识别器识别出的片段类型, 如 url, email, 普通的词为空
+	Type string
+	// Value 数值, 识别器实现了ValueRecognizer时有效, 如QuantityRecognizer识别出的"两块五"为2.5
+	Value float64
+	// Position/PositionLength 同lucene的token图, 所有token的起止偏移是图的节点, Position是Start对应的节点序号,
+	// PositionLength是token跨越的节点数. ModeSearch的结果是一条路径, Position依次加1, PositionLength为1;
+	// ModeIndex中"中华人民共和国"的Position和子词"中华"相同, PositionLength覆盖"中华"、"人民"、"共和国"等子词
+	Position       int
+	PositionLength int
 }
 
 func (st *SegToken) String() string {
-	return fmt.Sprintf("['%s', %d, %d]", st.Word, st.Start, st.End)
+	return fmt.Sprintf("['%s', %d, %d, '%s']", st.Word, st.Start, st.End, st.Pos)
+}
+
+// SegOptions 分词选项
+type SegOptions struct {
+	Mode ModeStyle
+	// DisableHmm 关闭hmm新词发现, 只使用词典分词, 同python jieba的HMM=False
+	DisableHmm bool
+	// SplitSingleRuns 同python jieba: 连续的单字组成词典中的词时逐字输出, 默认作为一个词输出;
+	// 关闭hmm时连续的单字也逐字输出, 只合并连续的英文数字, 默认同样在组成词典中的词时作为一个词输出
+	SplitSingleRuns bool
+	// 默认在匹配词典前把大写字母转小写、全角字符转半角、全角空格转半角空格, Keep*可以单独关闭其中的步骤.
+	// 词典是小写半角的, 关闭后这些字符不能匹配词典
+	KeepCase           bool
+	KeepFullWidth      bool
+	KeepFullWidthSpace bool
+	// Converter 分词前转换文本, 例如繁体转简体后匹配简体词典, token的Word和偏移仍然对应原文
+	Converter Converter
+	// Recognizers 分词前识别url、邮箱等特殊片段, 每个片段作为一个token, Type为片段的类型, 见DefaultRecognizers
+	Recognizers []Recognizer
+	// Ner 分词后识别人名、地名和机构名, 合并token并标注为nr、ns、nt, 见LoadNerModel和TrainNerModel
+	Ner *NerModel
+}
+
+// Converter 文本转换, 返回转换后的文本, 以及dst中每个字符在src中的区间, dst[i]由src[starts[i]:ends[i]]转换得到.
+// jiebag/opencc包的Converter实现了该接口
+type Converter interface {
+	ConvertRunes(src []rune) (dst []rune, starts, ends []int)
 }
 
 type sentenceTrace struct {
@@ -55,60 +130,335 @@ func (st *sentenceTrace) length() int {
 type SegmentHandler struct {
 	dict Trie
 	hmm  HmmSeg
+	// posHmm 词性hmm模型, 给hmm切分出的未登录词标注词性, 词典目录下没有pos模型时为nil, 此时按规则标注词性
+	posHmm posHmmSeg
+	// loadOpts 加载词库的选项, LoadOverlay时复用
+	loadOpts *dictload.Options
+}
+
+var ErrReadOnlyDict = errors.New("dict is read only")
+
+func (h *SegmentHandler) mutableDict(word string) (MutableTrie, error) {
+	if len(strings.TrimSpace(word)) == 0 {
+		return nil, errors.New("empty word")
+	}
+	dict, ok := h.dict.(MutableTrie)
+	if !ok {
+		return nil, ErrReadOnlyDict
+	}
+	return dict, nil
+}
+
+// AddWord 运行时添加词, freq <= 0 时使用SuggestFreq计算能使该词切分出来的词频, 可以与分词并发调用
+func (h *SegmentHandler) AddWord(word string, freq float64, pos string) error {
+	dict, err := h.mutableDict(word)
+	if err != nil {
+		return err
+	}
+	if freq <= 0 {
+		if freq, err = h.SuggestFreq(false, word); err != nil {
+			return err
+		}
+	}
+	dict.AddWord(word, freq, pos)
+	return nil
+}
+
+// DelWord 运行时删除词, 词不存在时返回false
+func (h *SegmentHandler) DelWord(word string) (bool, error) {
+	dict, err := h.mutableDict(word)
+	if err != nil {
+		return false, err
+	}
+	return dict.DelWord(word), nil
+}
+
+// LoadUserDict 运行时加载用户词典到h的词典, 同python jieba的load_userdict, 格式同用户词典, 见dictHeader, 优先级无效.
+// 没有词频时使用SuggestFreq计算, 词频为0表示删除该词. name是错误中的文件名, 格式错误的行按创建h时的dictload.Options处理
+func (h *SegmentHandler) LoadUserDict(r io.Reader, name string) error {
+	file, err := parseDict(r, name, false, h.loadOpts)
+	if err != nil {
+		return err
+	}
+	return h.applyDictFile(file)
+}
+
+func (h *SegmentHandler) applyDictFile(file *dictFile) error {
+	for _, entry := range file.entries {
+		if entry.freq == 0 {
+			if _, err := h.DelWord(entry.word); err != nil {
+				return err
+			}
+			continue
+		}
+		freq := entry.freq
+		if freq < 0 {
+			var err error
+			if freq, err = h.SuggestFreq(false, entry.word); err != nil {
+				return err
+			}
+		}
+		if err := h.AddWord(entry.word, freq*file.header.multiplier, entry.pos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Freq 词典中词的词频, 词不存在时返回false
+func (h *SegmentHandler) Freq(word string) (float64, bool) {
+	return h.dict.Freq(strings.ToLower(word))
+}
+
+// SuggestFreq 同python jieba的suggest_freq, 计算能使segment被切分出来的词频,
+// segment只有一个时使它作为一个整体, 多个时使它们被切开, tune为true时把计算的词频更新到词典
+func (h *SegmentHandler) SuggestFreq(tune bool, segment ...string) (float64, error) {
+	if len(segment) == 0 {
+		return 0, errors.New("empty segment")
+	}
+	word := strings.Join(segment, "")
+	dict := h.dict
+	total := dict.Total()
+	getFreq := func(w string, def float64) float64 {
+		if freq, ok := dict.Freq(strings.ToLower(w)); ok {
+			return freq
+		}
+		return def
+	}
+
+	freq := 1.0
+	var suggest float64
+	if len(segment) == 1 {
+		// 同python按cut(HMM=False)逐字切分, 算出的词频在默认选项下同样能使word作为整体
+		for _, token := range h.SegParagraphWithOptions(word, &SegOptions{DisableHmm: true, SplitSingleRuns: true}) {
+			freq *= getFreq(token.Word, 1) / total
+		}
+		suggest = float64(int64(freq*total)) + 1
+		if current := getFreq(word, 1); current > suggest {
+			suggest = current
+		}
+	} else {
+		for _, seg := range segment {
+			freq *= getFreq(seg, 1) / total
+		}
+		suggest = float64(int64(freq * total))
+		if current := getFreq(word, 0); current < suggest {
+			suggest = current
+		}
+	}
+
+	if tune {
+		var err error
+		if suggest > 0 {
+			err = h.AddWord(word, suggest, h.wordPos(word))
+		} else {
+			_, err = h.DelWord(word)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return suggest, nil
 }
 
 func (h *SegmentHandler) SegParagraph(s string, mode ModeStyle) []*SegToken {
-	paragraph := []rune(s)
+	return h.SegParagraphWithOptions(s, &SegOptions{Mode: mode})
+}
 
-	var st sentenceTrace
-	var segTokens []*SegToken
+func (h *SegmentHandler) SegParagraphWithOptions(s string, opts *SegOptions) []*SegToken {
+	if opts == nil {
+		opts = &SegOptions{}
+	}
+	paragraph := []rune(s)
+	offsets := newOffsetTable(len(paragraph))
+	for i, r := range s {
+		offsets.add(i, r)
+	}
+	offsets.finish(len(s))
 
 	for i, r := range paragraph {
-		nr := regularize(r)
-		paragraph[i] = nr
-		if couldTrieSegSupport(nr) {
+		paragraph[i] = regularize(r, opts)
+	}
+	segTokens := h.segRunes(nil, paragraph, 0, opts)
+	offsets.fill(segTokens, 0, s)
+	setPositions(segTokens, 0)
+	return segTokens
+}
+
+// segRunes 对规范化后的paragraph分词, offset是paragraph在原文中的起始位置.
+// 先由识别器识别特殊片段, 其他部分在不能参与分词的字符处切分成句子后分词
+func (h *SegmentHandler) segRunes(segTokens []*SegToken, paragraph []rune, offset int, opts *SegOptions) []*SegToken {
+	var st sentenceTrace
+	for i := 0; i < len(paragraph); {
+		if recognizer, length, typ := recognize(opts.Recognizers, paragraph, i); length > 0 {
+			if st.length() > 0 {
+				segTokens = h.acceptSentence(segTokens, paragraph[st.from:st.to], offset+st.offset, opts)
+			}
+			segTokens = append(segTokens, newRecognizedToken(recognizer, paragraph[i:i+length], offset+i, typ))
+			i += length
+			st = sentenceTrace{from: i, to: i, offset: i}
+			continue
+		}
+		if couldTrieSegSupport(paragraph[i]) {
 			st.to++
+			i++
 			continue
 		}
 		if st.length() > 0 {
-			tokens := h.segSentence(paragraph[st.from:st.to])
-			segTokens = h.accept(segTokens, tokens, st.offset, mode)
+			segTokens = h.acceptSentence(segTokens, paragraph[st.from:st.to], offset+st.offset, opts)
 		}
 
+		segTokens = h.acceptSingle(segTokens, paragraph[i:i+1], offset+i)
+		i++
+		st = sentenceTrace{from: i, to: i, offset: i}
+	}
+
+	if st.length() > 0 {
+		segTokens = h.acceptSentence(segTokens, paragraph[st.from:st.to], offset+st.offset, opts)
+	}
+	return segTokens
+}
+
+// acceptSingle 不能参与分词的字符单独作为一个token
+func (h *SegmentHandler) acceptSingle(segTokens []*SegToken, single []rune, offset int) []*SegToken {
+	return append(segTokens, &SegToken{
+		Word:  string(single),
+		Start: offset,
+		End:   offset + 1,
+		Pos:   PosUnknown,
+	})
+}
+
+func (h *SegmentHandler) acceptSentence(segTokens []*SegToken, sentence []rune, offset int, opts *SegOptions) []*SegToken {
+	if opts.Converter != nil {
+		return h.acceptConverted(segTokens, sentence, offset, opts)
+	}
+	if opts.Mode == ModeFull {
+		return h.acceptFull(segTokens, sentence, offset)
+	}
+	tokens := h.segSentence(sentence, opts)
+	if opts.Ner != nil {
+		tokens = h.tagEntities(tokens, opts.Ner)
+	}
+	return h.accept(segTokens, tokens, offset, opts.Mode)
+}
+
+// acceptConverted 对转换后的文本分词, 再把token映射回原文
+func (h *SegmentHandler) acceptConverted(segTokens []*SegToken, sentence []rune, offset int, opts *SegOptions) []*SegToken {
+	converted, starts, ends := opts.Converter.ConvertRunes(sentence)
+	plain := *opts
+	plain.Converter = nil
+	tokens := h.acceptSentence(nil, converted, 0, &plain)
+	for _, token := range tokens {
+		start, end := starts[token.Start], ends[token.End-1]
+		token.Word = string(sentence[start:end])
+		token.Start = offset + start
+		token.End = offset + end
+	}
+	return append(segTokens, tokens...)
+}
+
+// acceptFull 全模式, 输出DAG中所有长度大于1的词, 没有被词覆盖的单字单独输出, 连续的英文数字合并成一个词
+func (h *SegmentHandler) acceptFull(segTokens []*SegToken, sentence []rune, offset int) []*SegToken {
+	f := func(start, end int) {
+		word := string(sentence[start:end])
 		segTokens = append(segTokens, &SegToken{
-			Word:  string(paragraph[i : i+1]),
-			Start: i,
-			End:   i + 1,
+			Word:  word,
+			Start: offset + start,
+			End:   offset + end,
+			Pos:   h.wordPos(word),
 		})
-		st.from = i + 1
-		st.to = i + 1
-		st.offset = i + 1
 	}
 
-	if st.length() > 0 {
-		tokens := h.segSentence(paragraph[st.from:st.to])
-		segTokens = h.accept(segTokens, tokens, st.offset, mode)
+	covered := 0
+	engStart, engEnd := -1, -1
+	for k, candidates := range h.dict.MatchAll(sentence) {
+		if engStart >= 0 && !isEnglish(sentence[k]) && !isDigit(sentence[k]) {
+			f(engStart, engEnd)
+			engStart = -1
+		}
+		if len(candidates) == 1 && k >= covered {
+			seg := candidates[0]
+			if isEnglish(sentence[k]) || isDigit(sentence[k]) {
+				if engStart < 0 {
+					engStart = k
+				}
+				engEnd = seg.End
+			}
+			if engStart < 0 {
+				f(seg.Start, seg.End)
+			}
+			covered = seg.End
+			continue
+		}
+		for _, seg := range candidates {
+			if seg.len() > 1 {
+				f(seg.Start, seg.End)
+				covered = seg.End
+			}
+		}
+	}
+	if engStart >= 0 {
+		f(engStart, engEnd)
 	}
 	return segTokens
 }
 
-func (h *SegmentHandler) accept(segTokens []*SegToken, tokens []string, offset int, mode ModeStyle) []*SegToken {
+func (h *SegmentHandler) accept(segTokens []*SegToken, tokens []*wordTag, offset int, mode ModeStyle) []*SegToken {
 	for _, token := range tokens {
-		tokenArray := []rune(token)
+		tokenArray := []rune(token.word)
 		if mode == ModeIndex {
 			segTokens = h.acceptShort(segTokens, tokenArray, offset)
 		}
 		end := offset + len(tokenArray)
 		segTokens = append(segTokens, &SegToken{
-			Word:  token,
+			Word:  token.word,
 			Start: offset,
 			End:   end,
+			Pos:   token.pos,
 		})
 		offset = end
 	}
 
 	return segTokens
 }
+
+// setPositions 按Start排序, Start相同时短的在前, 然后计算Position和PositionLength.
+// position是第一个节点的序号, 返回最后一个节点的序号, 即下一段token的position
+func setPositions(tokens []*SegToken, position int) int {
+	if len(tokens) == 0 {
+		return position
+	}
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].Start != tokens[j].Start {
+			return tokens[i].Start < tokens[j].Start
+		}
+		return tokens[i].End < tokens[j].End
+	})
+	from, to := tokens[0].Start, tokens[0].End
+	for _, token := range tokens {
+		if token.End > to {
+			to = token.End
+		}
+	}
+	// nodes[i] 偏移from+i对应的节点序号
+	nodes := make([]int, to-from+1)
+	for _, token := range tokens {
+		nodes[token.Start-from] = 1
+		nodes[token.End-from] = 1
+	}
+	node := position - 1
+	for i, boundary := range nodes {
+		node += boundary
+		nodes[i] = node
+	}
+	for _, token := range tokens {
+		token.Position = nodes[token.Start-from]
+		token.PositionLength = nodes[token.End-from] - token.Position
+	}
+	return nodes[len(nodes)-1]
+}
+
 func (h *SegmentHandler) acceptShort(segTokens []*SegToken, token []rune, offset int) []*SegToken {
 	l := len(token)
 	if l <= 2 {
@@ -123,6 +473,7 @@ func (h *SegmentHandler) acceptShort(segTokens []*SegToken, token []rune, offset
 						Word:  gram,
 						Start: offset + j,
 						End:   offset + j + step,
+						Pos:   h.wordPos(gram),
 					})
 				}
 			}
@@ -133,21 +484,25 @@ func (h *SegmentHandler) acceptShort(segTokens []*SegToken, token []rune, offset
 	return segTokens
 }
 
-func (h *SegmentHandler) segSentence(sentence []rune) []string {
+func (h *SegmentHandler) segSentence(sentence []rune, opts *SegOptions) []*wordTag {
 	dict := h.dict
-	hmm := h.hmm
 	segments := dict.Match(sentence)
 
-	var tokens []string
+	var tokens []*wordTag
 
 	f := func(needHmmStat []rune) {
 		word := string(needHmmStat)
-		// is is a word, but it is ignored because another cut path is best
-		if dict.ExistShortWord(word) {
-			tokens = append(tokens, word)
-		} else {
+		isWord := dict.ExistShortWord(word)
+		switch {
+		case isWord && !opts.SplitSingleRuns:
+			// is is a word, but it is ignored because another cut path is best
+			tokens = append(tokens, &wordTag{word: word, pos: h.wordPos(word)})
+		case isWord || opts.DisableHmm:
+			// 同python jieba逐字输出, 不交给hmm
+			tokens = h.cutWithoutHmm(needHmmStat, tokens)
+		default:
 			// call hmm
-			tokens = append(tokens, hmm.Cut(needHmmStat)...)
+			tokens = h.cutHmm(needHmmStat, tokens)
 		}
 	}
 
@@ -162,7 +517,8 @@ func (h *SegmentHandler) segSentence(sentence []rune) []string {
 			f(needHmmStat)
 		}
 
-		tokens = append(tokens, string(sentence[seg.Start:seg.End]))
+		word := string(sentence[seg.Start:seg.End])
+		tokens = append(tokens, &wordTag{word: word, pos: h.wordPos(word)})
 		st.from = seg.End
 		st.to = seg.End
 	}
@@ -174,3 +530,54 @@ func (h *SegmentHandler) segSentence(sentence []rune) []string {
 
 	return tokens
 }
+
+// cutHmm 使用hmm切分未登录词, 有词性模型时再用词性模型标注词性, 词性模型不改变切分
+func (h *SegmentHandler) cutHmm(needHmmStat []rune, tokens []*wordTag) []*wordTag {
+	words := h.hmm.Cut(needHmmStat)
+	if h.posHmm != nil {
+		return append(tokens, h.posHmm.TagWords(words)...)
+	}
+	for _, word := range words {
+		tokens = append(tokens, &wordTag{word: word, pos: h.wordPos(word)})
+	}
+	return tokens
+}
+
+// cutWithoutHmm 不使用hmm时, 词典外的片段逐字输出, 连续的英文数字合并为一个词
+func (h *SegmentHandler) cutWithoutHmm(needHmmStat []rune, tokens []*wordTag) []*wordTag {
+	f := func(word string) {
+		tokens = append(tokens, &wordTag{word: word, pos: h.wordPos(word)})
+	}
+	engStart := -1
+	for i, r := range needHmmStat {
+		if isEnglish(r) || isDigit(r) {
+			if engStart < 0 {
+				engStart = i
+			}
+			continue
+		}
+		if engStart >= 0 {
+			f(string(needHmmStat[engStart:i]))
+			engStart = -1
+		}
+		f(string(needHmmStat[i : i+1]))
+	}
+	if engStart >= 0 {
+		f(string(needHmmStat[engStart:]))
+	}
+	return tokens
+}
+
+// wordPos 词典中的词使用词典标注的词性, 其他按字符类型标注
+func (h *SegmentHandler) wordPos(word string) string {
+	if pos, ok := h.dict.Pos(word); ok && pos != "" {
+		return pos
+	}
+	for _, r := range word {
+		if isCjk(r) {
+			return PosUnknown
+		}
+		break
+	}
+	return otherWordPos(word)
+}
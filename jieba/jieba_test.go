@@ -4,8 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"unicode/utf16"
+
+	"github.com/rolandhe/jiebag/opencc"
 )
 
 var sentenceCases = []string{
@@ -167,3 +173,408 @@ func TestMatch(t *testing.T) {
 	rjson, _ := json.Marshal(tokens)
 	fmt.Printf("%s\n", string(rjson))
 }
+
+func loadTestHandler(t *testing.T) *SegmentHandler {
+	fixture := os.DirFS("../test/jieba")
+	trie, err := newDict(fixture, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmm, err := newHmmSeg(os.DirFS("../dict"), BaseProbName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	posHmm, err := newPosHmmSeg(fixture, PosModelDirName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SegmentHandler{
+		dict:   trie,
+		hmm:    hmm,
+		posHmm: posHmm,
+	}
+}
+
+func tokenWords(tokens []*SegToken) []string {
+	var words []string
+	for _, token := range tokens {
+		words = append(words, token.Word)
+	}
+	return words
+}
+
+func TestPosTag(t *testing.T) {
+	handler := loadTestHandler(t)
+
+	tokens := handler.SegParagraph("我来到北京清华大学，王刚说iphone5", ModeSearch)
+	fmt.Printf("%s\n", tokens)
+
+	expect := []string{"我/r", "来到/v", "北京/ns", "清华大学/nt", ",/x", "王刚/nr", "说/v", "iphone5/eng"}
+	if len(tokens) != len(expect) {
+		t.Fatalf("expect %v, got %s", expect, tokens)
+	}
+	for i, token := range tokens {
+		if token.Word+"/"+token.Pos != expect[i] {
+			t.Errorf("expect %s, got %s/%s", expect[i], token.Word, token.Pos)
+		}
+	}
+}
+
+func TestPosTagWithoutModel(t *testing.T) {
+	handler := loadTestHandler(t)
+	handler.posHmm = nil
+
+	tokens := handler.SegParagraph("小明硕士毕业于中国科学院计算所, 99元", ModeSearch)
+	fmt.Printf("%s\n", tokens)
+	for _, token := range tokens {
+		if token.Pos == "" {
+			t.Errorf("%s has no pos", token.Word)
+		}
+	}
+}
+
+func TestPosHmmWithoutStates(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, name := range []string{posProbStartName, posProbTransName, posProbEmitName, posCharStateName} {
+		fsys[PosModelDirName+"/"+name] = &fstest.MapFile{}
+	}
+	posHmm, err := newPosHmmSeg(fsys, PosModelDirName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posHmm != nil {
+		t.Errorf("expect nil model, got %T", posHmm)
+	}
+
+	// 没有状态时按规则标注, 不会panic
+	tokens := (&posHmmImpl{}).TagWords([]string{"韩玉赏鉴", "99"})
+	if len(tokens) != 2 || tokens[0].word != "韩玉赏鉴" || tokens[0].pos != PosUnknown || tokens[1].pos != PosNumber {
+		t.Errorf("bad tokens %v", tokens)
+	}
+}
+
+func TestCutAll(t *testing.T) {
+	handler := loadTestHandler(t)
+
+	tokens := handler.SegParagraph("我来到北京清华大学", ModeFull)
+	fmt.Printf("%s\n", tokens)
+	expect := []string{"我", "来到", "北京", "清华", "清华大学", "华大", "大学"}
+	if fmt.Sprint(tokenWords(tokens)) != fmt.Sprint(expect) {
+		t.Errorf("expect %v, got %v", expect, tokenWords(tokens))
+	}
+
+	tokens = handler.SegParagraph("中华人民共和国iphone5", ModeFull)
+	fmt.Printf("%s\n", tokens)
+	expect = []string{"中华", "中华人民共和国", "华人", "人民", "人民共和国", "共和", "共和国", "iphone5"}
+	if fmt.Sprint(tokenWords(tokens)) != fmt.Sprint(expect) {
+		t.Errorf("expect %v, got %v", expect, tokenWords(tokens))
+	}
+	if tokens[4].Start != 2 || tokens[4].End != 7 {
+		t.Errorf("bad offset %s", tokens[4])
+	}
+}
+
+func TestDisableHmm(t *testing.T) {
+	handler := loadTestHandler(t)
+	sentence := "他来到了网易杭研大厦, iphone5很好"
+
+	withHmm := handler.SegParagraphWithOptions(sentence, &SegOptions{Mode: ModeSearch})
+	withoutHmm := handler.SegParagraphWithOptions(sentence, &SegOptions{Mode: ModeSearch, DisableHmm: true})
+	fmt.Printf("%s\n%s\n", withHmm, withoutHmm)
+
+	expect := []string{"他", "来到", "了", "网易", "杭研", "大厦", ",", " ", "iphone5", "很", "好"}
+	if fmt.Sprint(tokenWords(withoutHmm)) != fmt.Sprint(expect) {
+		t.Errorf("expect %v, got %v", expect, tokenWords(withoutHmm))
+	}
+	runes := []rune(sentence)
+	for _, tokens := range [][]*SegToken{withHmm, withoutHmm} {
+		offset := 0
+		for _, token := range tokens {
+			if token.Start != offset || string(runes[token.Start:token.End]) != token.Word {
+				t.Fatalf("bad offset %s", token)
+			}
+			offset = token.End
+		}
+		if offset != len(runes) {
+			t.Fatalf("tokens do not cover %s", sentence)
+		}
+	}
+}
+
+func TestSplitSingleRuns(t *testing.T) {
+	handler := loadTestHandler(t)
+	// 来去的词频低于来和去的组合, 最优路径上是连续的单字
+	if err := handler.AddWord("来去", 1, "v"); err != nil {
+		t.Fatal(err)
+	}
+	sentence := "网易来去北京"
+
+	cases := []struct {
+		opts   *SegOptions
+		expect []string
+	}{
+		{&SegOptions{}, []string{"网易", "来去", "北京"}},
+		{&SegOptions{DisableHmm: true}, []string{"网易", "来去", "北京"}},
+		{&SegOptions{SplitSingleRuns: true}, []string{"网易", "来", "去", "北京"}},
+		{&SegOptions{DisableHmm: true, SplitSingleRuns: true}, []string{"网易", "来", "去", "北京"}},
+	}
+	for _, c := range cases {
+		tokens := handler.SegParagraphWithOptions(sentence, c.opts)
+		fmt.Println(tokens)
+		if fmt.Sprint(tokenWords(tokens)) != fmt.Sprint(c.expect) {
+			t.Errorf("%+v: expect %v, got %v", *c.opts, c.expect, tokenWords(tokens))
+		}
+	}
+}
+
+func TestEditDict(t *testing.T) {
+	handler := loadTestHandler(t)
+	opts := &SegOptions{DisableHmm: true}
+
+	if err := handler.AddWord("杭研大厦", 0, "nt"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := handler.SegParagraphWithOptions("网易杭研大厦", opts)
+	if fmt.Sprint(tokenWords(tokens)) != "[网易 杭研大厦]" || tokens[1].Pos != "nt" {
+		t.Errorf("add word failed: %s", tokens)
+	}
+
+	if ok, _ := handler.DelWord("杭研大厦"); !ok {
+		t.Error("del word failed")
+	}
+	tokens = handler.SegParagraphWithOptions("网易杭研大厦", opts)
+	if fmt.Sprint(tokenWords(tokens)) != "[网易 杭研 大厦]" {
+		t.Errorf("del word failed: %s", tokens)
+	}
+
+	freq, err := handler.SuggestFreq(true, "中国", "科学院")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log("suggest freq", freq)
+	tokens = handler.SegParagraphWithOptions("中国科学院", opts)
+	if fmt.Sprint(tokenWords(tokens)) != "[中国 科学院]" {
+		t.Errorf("suggest freq failed: %s", tokens)
+	}
+
+	if _, err = handler.SuggestFreq(true, "中国科学院"); err != nil {
+		t.Fatal(err)
+	}
+	tokens = handler.SegParagraphWithOptions("中国科学院", opts)
+	if fmt.Sprint(tokenWords(tokens)) != "[中国科学院]" {
+		t.Errorf("suggest freq failed: %s", tokens)
+	}
+}
+
+func TestEditDictConcurrent(t *testing.T) {
+	handler := loadTestHandler(t)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_ = handler.AddWord("杭研大厦", 100, "nt")
+			_, _ = handler.DelWord("杭研大厦")
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		handler.SegParagraph("网易杭研大厦", ModeIndex)
+	}
+	<-done
+}
+
+func TestSegmentHandlerFS(t *testing.T) {
+	probEmit, err := os.ReadFile("../dict/prob_emit.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		BaseDictName:                   {Data: []byte("北京 34488 ns\n清华大学 2053 nt\n大学 20025 n\n")},
+		BaseProbName:                   {Data: probEmit},
+		UserDictDirName + "/user.dict": {Data: []byte("天安门 3 ns\n")},
+		IdfStopWordsName:               {Data: []byte("的\n")},
+		IdfDictName:                    {Data: []byte("北京 5.5\n")},
+	}
+	handler, err := NewSegmentHandlerFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := handler.SegParagraph("北京天安门清华大学", ModeSearch)
+	if fmt.Sprint(tokenWords(tokens)) != "[北京 天安门 清华大学]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+	if _, err = NewTfidfFS(fsys, handler); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	handler := loadTestHandler(t)
+	content := "我来到😀北京ABC清华大学，\xff南京市长江大桥"
+	units := utf16.Encode([]rune(content))
+
+	tokens := handler.SegParagraph(content, ModeIndex)
+	for _, token := range tokens {
+		fmt.Println(token, token.ByteStart, token.ByteEnd, token.Utf16Start, token.Utf16End)
+		// 非法的utf8字节作为一个rune
+		raw := []rune(content)[token.Start:token.End]
+		if string([]rune(content[token.ByteStart:token.ByteEnd])) != string(raw) {
+			t.Errorf("bad byte offset %s: %q", token, content[token.ByteStart:token.ByteEnd])
+		}
+		if token.Text != content[token.ByteStart:token.ByteEnd] {
+			t.Errorf("bad text %s: %q", token, token.Text)
+		}
+		if string(utf16.Decode(units[token.Utf16Start:token.Utf16End])) != string(raw) {
+			t.Errorf("bad utf16 offset %s", token)
+		}
+	}
+	if last := tokens[len(tokens)-1]; last.ByteEnd != len(content) || last.Utf16End != len(units) {
+		t.Errorf("bad end offset %d %d", last.ByteEnd, last.Utf16End)
+	}
+
+	stream := handler.NewTokenStream(strings.NewReader(content), &SegOptions{Mode: ModeIndex})
+	for i := 0; stream.Next(); i++ {
+		if *stream.Token() != *tokens[i] {
+			t.Errorf("stream token %d differs: %+v, %+v", i, stream.Token(), tokens[i])
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	handler := loadTestHandler(t)
+	content := "我来到中华人民共和国北京"
+	format := func(tokens []*SegToken) string {
+		var items []string
+		for _, token := range tokens {
+			items = append(items, fmt.Sprintf("%s@%d:%d", token.Word, token.Position, token.PositionLength))
+		}
+		return strings.Join(items, " ")
+	}
+
+	cases := []struct {
+		mode   ModeStyle
+		expect string
+	}{
+		{ModeSearch, "我@0:1 来到@1:1 中华人民共和国@2:1 北京@3:1"},
+		// 子词按起始位置排序, 中华->人民->共和国 和 中华人民共和国 从同一个节点开始, 在同一个节点结束
+		{ModeIndex, "我@0:1 来到@1:1 中华@2:2 中华人民共和国@2:6 华人@3:2 人民@4:2 共和@6:1 共和国@6:2 北京@8:1"},
+	}
+	for _, c := range cases {
+		tokens := handler.SegParagraph(content, c.mode)
+		fmt.Println(format(tokens))
+		if got := format(tokens); got != c.expect {
+			t.Errorf("expect %s, got %s", c.expect, got)
+		}
+	}
+
+	// 流式分词的Position在句子之间连续
+	stream := handler.NewTokenStream(strings.NewReader(content+"，"+content), &SegOptions{Mode: ModeIndex})
+	var got []*SegToken
+	for stream.Next() {
+		got = append(got, stream.Token())
+	}
+	if last := got[len(got)-1]; last.Word != "北京" || last.Position != 18 {
+		t.Errorf("bad stream position %s@%d", last, last.Position)
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	handler := loadTestHandler(t)
+	content := "iPhone５　ＡＢＣ"
+
+	cases := []struct {
+		opts  *SegOptions
+		words string
+	}{
+		{&SegOptions{}, "[iphone5   abc]"},
+		{&SegOptions{KeepCase: true}, "[iPhone5   ABC]"},
+		{&SegOptions{KeepFullWidth: true}, "[iphone ５   Ａ Ｂ Ｃ]"},
+		{&SegOptions{KeepFullWidthSpace: true}, "[iphone5 　 abc]"},
+	}
+	for _, c := range cases {
+		tokens := handler.SegParagraphWithOptions(content, c.opts)
+		if fmt.Sprint(tokenWords(tokens)) != c.words {
+			t.Errorf("%+v: expect %s, got %s", c.opts, c.words, tokenWords(tokens))
+		}
+		for _, token := range tokens {
+			if token.Text != content[token.ByteStart:token.ByteEnd] {
+				t.Errorf("bad text %s: %q", token, token.Text)
+			}
+		}
+	}
+
+	tokens := handler.SegParagraph(content, ModeSearch)
+	if tokens[0].Word != "iphone5" || tokens[0].Text != "iPhone５" {
+		t.Errorf("bad token %q %q", tokens[0].Word, tokens[0].Text)
+	}
+}
+
+func TestConverter(t *testing.T) {
+	handler := loadTestHandler(t)
+	converter, err := opencc.Default(opencc.T2S)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := "我來到北京清華大學，長江大橋"
+	opts := &SegOptions{Mode: ModeIndex, Converter: converter}
+
+	tokens := handler.SegParagraphWithOptions(content, opts)
+	fmt.Println(tokens)
+	if fmt.Sprint(tokenWords(tokens)) != "[我 來到 北京 清華 清華大學 華大 大學 , 長江 長江大橋 江大橋 大橋]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+	for _, token := range tokens {
+		if token.Text != string([]rune(content)[token.Start:token.End]) {
+			t.Errorf("bad offset %s", token)
+		}
+	}
+	if tokens[1].Pos != "v" || tokens[4].Pos != "nt" {
+		t.Errorf("bad pos %s %s", tokens[1], tokens[4])
+	}
+
+	stream := handler.NewTokenStream(strings.NewReader(content), opts)
+	for i := 0; stream.Next(); i++ {
+		if *stream.Token() != *tokens[i] {
+			t.Errorf("stream token %d differs: %+v, %+v", i, stream.Token(), tokens[i])
+		}
+	}
+}
+
+func TestCjkRanges(t *testing.T) {
+	for _, r := range "〇㐀中豈𠮷𪜀𫝀𫠠𬺰𮯰丽𰀀𱍐" {
+		if !isCjk(r) {
+			t.Errorf("expect %c(%U) is cjk", r, r)
+		}
+	}
+	for _, r := range "a，。ぁ가" {
+		if isCjk(r) {
+			t.Errorf("expect %c(%U) is not cjk", r, r)
+		}
+	}
+
+	handler := loadTestHandler(t)
+	if err := handler.AddWord("𠮷野家", 100, "nt"); err != nil {
+		t.Fatal(err)
+	}
+	tokens := handler.SegParagraph("我来到𠮷野家", ModeSearch)
+	if fmt.Sprint(tokenWords(tokens)) != "[我 来到 𠮷野家]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+
+	// AddCjkRanges是全局的, 测试结束后恢复, 不影响其他测试
+	saved := cjkRanges.Load()
+	t.Cleanup(func() {
+		cjkRanges.Store(saved)
+	})
+	AddCjkRanges(CharRange{Lo: 0xE000, Hi: 0xE0FF}, CharRange{Lo: 0xE100, Hi: 0xE1FF})
+	if !isCjk(0xE1FF) || isCjk(0xE200) {
+		t.Errorf("bad added ranges")
+	}
+	// 私有区的造字
+	if err := handler.AddWord("\ue000\ue101", 100, "nr"); err != nil {
+		t.Fatal(err)
+	}
+	tokens = handler.SegParagraph("我来到\ue000\ue101", ModeSearch)
+	if len(tokens) != 3 || tokens[2].Word != "\ue000\ue101" || tokens[2].Pos != "nr" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
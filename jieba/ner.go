@@ -0,0 +1,248 @@
+package jieba
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	PosPerson = "nr"
+	PosPlace  = "ns"
+	PosOrg    = "nt"
+)
+
+const (
+	maxGivenName  = 2
+	maxPlacePart  = 4
+	minOrgPrefix  = 2
+	maxOrgPrefix  = 8
+	maxEntitySpan = 12
+	maxTitle      = 4
+	// defaultGivenProb 不在名字用字表中的字作为名字用字的概率
+	defaultGivenProb = 0.05
+	// titleWeight 称谓后的人名的加分系数
+	titleWeight = 4
+)
+
+// NerModel 命名实体识别模型, 在分词结果上识别人名、地名和机构名, 合并token并标注为nr、ns、nt.
+// 人名使用姓氏和名字用字的概率打分, 地名和机构名使用后缀识别.
+// 可以使用dict.LoadNerModel加载内置的模型, 或者使用TrainNerModel从标注语料训练
+type NerModel struct {
+	// surnames 字或词作为姓氏的概率, 包括复姓
+	surnames map[string]float64
+	// given 字作为名字用字的概率
+	given map[rune]float64
+	// titles 称谓后面是人名的概率, 如 记者 发言人
+	titles map[string]float64
+	// places 地名后缀, 如 市 省 路
+	places map[string]float64
+	// orgs 机构名后缀, 如 公司 大学 部
+	orgs map[string]float64
+}
+
+func newNerModel() *NerModel {
+	return &NerModel{
+		surnames: map[string]float64{},
+		given:    map[rune]float64{},
+		titles:   map[string]float64{},
+		places:   map[string]float64{},
+		orgs:     map[string]float64{},
+	}
+}
+
+// nerStopChars 不能出现在地名、机构名中的单字token
+var nerStopChars = map[string]bool{
+	"的": true, "了": true, "在": true, "是": true, "和": true, "与": true, "及": true, "或": true,
+	"我": true, "你": true, "他": true, "她": true, "它": true, "们": true, "这": true, "那": true,
+	"有": true, "要": true, "去": true, "来": true, "到": true, "说": true, "也": true, "都": true,
+	"就": true, "还": true, "把": true, "被": true, "给": true, "对": true, "从": true, "向": true,
+	"为": true, "以": true, "而": true, "但": true, "不": true, "没": true, "很": true, "又": true,
+	"一": true, "个": true, "些": true, "各": true, "每": true, "该": true, "本": true, "此": true,
+	"其": true, "等": true, "之": true, "着": true, "过": true, "全": true,
+}
+
+// entityPos 地名、机构名中可以包含的词典词的词性, 其他词性的词典词不能作为实体的一部分
+var entityPos = map[string]bool{
+	"n": true, "vn": true, "an": true, "ng": true, "j": true, "nz": true, "x": true, "f": true,
+	PosPerson: true, PosPlace: true, PosOrg: true, "nrt": true, "nrfg": true,
+}
+
+// tagEntities 在segSentence的结果上识别命名实体, 从左到右取最长的实体, 长度相同时优先机构名、地名
+func (h *SegmentHandler) tagEntities(tokens []*wordTag, model *NerModel) []*wordTag {
+	words := make([][]rune, len(tokens))
+	for i, token := range tokens {
+		words[i] = []rune(token.word)
+	}
+	result := make([]*wordTag, 0, len(tokens))
+	for i := 0; i < len(tokens); {
+		// 前面的几个字, 用于匹配称谓, 关闭hmm时称谓可能被切分成单字
+		var prev []rune
+		for j := len(result) - 1; j >= 0 && len(prev) < maxTitle; j-- {
+			prev = append([]rune(result[j].word), prev...)
+		}
+		end, pos := h.matchOrg(tokens, words, i, model), PosOrg
+		if e := h.matchPlace(tokens, words, i, model); e > end {
+			end, pos = e, PosPlace
+		}
+		if e := h.matchPerson(tokens, words, i, prev, model); e > end {
+			end, pos = e, PosPerson
+		}
+		if end == 0 {
+			result = append(result, tokens[i])
+			i++
+			continue
+		}
+		var word strings.Builder
+		for _, token := range tokens[i:end] {
+			word.WriteString(token.word)
+		}
+		result = append(result, &wordTag{word: word.String(), pos: pos})
+		i = end
+	}
+	return result
+}
+
+// fixedWord 词典中的多字词, 词性不在allowed中时不能拆开或合并
+func (h *SegmentHandler) fixedWord(token *wordTag, word []rune, allowed func(pos string) bool) bool {
+	if len(word) < 2 {
+		return false
+	}
+	pos, ok := h.dict.Pos(token.word)
+	return ok && !allowed(pos)
+}
+
+func isPersonPos(pos string) bool {
+	return strings.HasPrefix(pos, PosPerson)
+}
+
+func isEntityPos(pos string) bool {
+	return entityPos[pos]
+}
+
+func allCjk(word []rune) bool {
+	for _, r := range word {
+		if !isCjk(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// spanEnd 从tokens[i]开始、长度为length的片段结束的token下标, 片段没有结束在token边界时返回0
+func spanEnd(words [][]rune, i, length int) int {
+	for j := i; j < len(words) && length > 0; j++ {
+		length -= len(words[j])
+		if length == 0 {
+			return j + 1
+		}
+	}
+	return 0
+}
+
+func logit(p float64) float64 {
+	p = math.Min(math.Max(p, 1e-6), 1-1e-6)
+	return math.Log(p / (1 - p))
+}
+
+// matchPerson 姓氏+1到2个名字用字, 得分为姓氏、名字用字概率的logit之和, 前面是称谓时加分, 返回得分大于0的最长的人名
+func (h *SegmentHandler) matchPerson(tokens []*wordTag, words [][]rune, i int, prev []rune, model *NerModel) int {
+	if !allCjk(words[i]) || h.fixedWord(tokens[i], words[i], isPersonPos) {
+		return 0
+	}
+	var text []rune
+	for j := i; j < len(words) && len(text) < 2+maxGivenName; j++ {
+		text = append(text, words[j]...)
+	}
+	bonus := 0.0
+	for l := 1; l <= maxTitle && l <= len(prev); l++ {
+		if p, ok := model.titles[string(prev[len(prev)-l:])]; ok {
+			bonus = titleWeight * p
+		}
+	}
+	for surnameLen := 2; surnameLen > 0; surnameLen-- {
+		if len(text) <= surnameLen {
+			continue
+		}
+		p, ok := model.surnames[string(text[:surnameLen])]
+		if !ok {
+			continue
+		}
+		score := logit(p) + bonus
+		best := 0
+		for g := 1; g <= maxGivenName && surnameLen+g <= len(text); g++ {
+			c := text[surnameLen+g-1]
+			if !isCjk(c) {
+				break
+			}
+			pg, ok := model.given[c]
+			if !ok {
+				pg = defaultGivenProb
+			}
+			score += logit(pg)
+			end := spanEnd(words, i, surnameLen+g)
+			if end == 0 || score <= 0 || !h.freeTokens(tokens, words, i+1, end, isPersonPos) {
+				continue
+			}
+			best = end
+		}
+		if best > 0 {
+			return best
+		}
+	}
+	return 0
+}
+
+// freeTokens tokens[from:to]中没有不能合并的词典词
+func (h *SegmentHandler) freeTokens(tokens []*wordTag, words [][]rune, from, to int, allowed func(pos string) bool) bool {
+	for j := from; j < to; j++ {
+		if h.fixedWord(tokens[j], words[j], allowed) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSuffix 从tokens[i]开始以suffixes中的后缀结尾的最长片段, 后缀前的部分长度在[minPrefix, maxPrefix]之间
+func (h *SegmentHandler) matchSuffix(tokens []*wordTag, words [][]rune, i int, suffixes map[string]float64, minPrefix, maxPrefix int) int {
+	best := 0
+	var text []rune
+	for j := i; j < len(words); j++ {
+		word := words[j]
+		if !allCjk(word) || (len(word) == 1 && nerStopChars[tokens[j].word]) || h.fixedWord(tokens[j], word, isEntityPos) {
+			break
+		}
+		text = append(text, word...)
+		if len(text) > maxEntitySpan {
+			break
+		}
+		// 单独一个词典词不需要识别
+		if suffixEnd(text, suffixes, minPrefix, maxPrefix) && (j > i || !h.dict.ExistShortWord(tokens[i].word)) {
+			best = j + 1
+		}
+	}
+	return best
+}
+
+// suffixEnd text以后缀结尾并且前面部分的长度合适
+func suffixEnd(text []rune, suffixes map[string]float64, minPrefix, maxPrefix int) bool {
+	for l := 3; l > 0; l-- {
+		prefix := len(text) - l
+		if prefix < minPrefix || prefix > maxPrefix {
+			continue
+		}
+		if _, ok := suffixes[string(text[prefix:])]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPlace 地名, 如 上海市 徐汇区 漕溪北路
+func (h *SegmentHandler) matchPlace(tokens []*wordTag, words [][]rune, i int, model *NerModel) int {
+	return h.matchSuffix(tokens, words, i, model.places, 1, maxPlacePart)
+}
+
+// matchOrg 机构名, 如 外交部 上海浦东发展银行
+func (h *SegmentHandler) matchOrg(tokens []*wordTag, words [][]rune, i int, model *NerModel) int {
+	return h.matchSuffix(tokens, words, i, model.orgs, minOrgPrefix, maxOrgPrefix)
+}
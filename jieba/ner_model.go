@@ -0,0 +1,296 @@
+package jieba
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+// NerModelName 词库目录下的命名实体识别模型文件
+const NerModelName = "ner.txt"
+
+const (
+	nerSurname = "surname"
+	nerGiven   = "given"
+	nerTitle   = "title"
+	nerPlace   = "place"
+	nerOrg     = "org"
+)
+
+const (
+	// nerMinCount 训练时出现次数少于该值的字和词不进入模型
+	nerMinCount = 2
+	// nerMinTitle 称谓后面是人名的概率的最小值
+	nerMinTitle = 0.1
+	// nerMinSuffix 以后缀结尾的词是地名/机构名的概率的最小值
+	nerMinSuffix = 0.5
+	maxNerSuffix = 3
+)
+
+// LoadNerModel 从fsys加载模型, 每行: 类型 字或词 概率, 类型为surname、given、title、place、org, #开头的行是注释, 见dict/ner.txt
+func LoadNerModel(fsys fs.FS, name string) (*NerModel, error) {
+	return LoadNerModelWithOptions(fsys, name, nil)
+}
+
+// LoadNerModelWithOptions 同LoadNerModel, 通过opts选择严格或宽松模式, 见dictload.Options
+func LoadNerModelWithOptions(fsys fs.FS, name string, opts *dictload.Options) (*NerModel, error) {
+	model := newNerModel()
+	err := scanModelFile(fsys, name, opts, func(items []string) error {
+		if strings.HasPrefix(items[0], "#") {
+			return dictload.ErrSkip
+		}
+		if len(items) != 3 {
+			return errors.New("bad ner items:" + strings.Join(items, " "))
+		}
+		p, err := strconv.ParseFloat(items[2], 64)
+		if err != nil {
+			return err
+		}
+		return model.set(items[0], items[1], p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+func (m *NerModel) set(typ, key string, p float64) error {
+	switch typ {
+	case nerSurname:
+		m.surnames[key] = p
+	case nerGiven:
+		r, size := utf8.DecodeRuneInString(key)
+		if size != len(key) {
+			return errors.New("given name char must be one rune:" + key)
+		}
+		m.given[r] = p
+	case nerTitle:
+		m.titles[key] = p
+	case nerPlace:
+		m.places[key] = p
+	case nerOrg:
+		m.orgs[key] = p
+	default:
+		return errors.New("unknown ner type:" + typ)
+	}
+	return nil
+}
+
+// Save 按LoadNerModel的格式保存模型
+func (m *NerModel) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	write := func(typ string, table map[string]float64) {
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(bw, "%s %s %.4g\n", typ, key, table[key])
+		}
+	}
+	given := make(map[string]float64, len(m.given))
+	for r, p := range m.given {
+		given[string(r)] = p
+	}
+	write(nerSurname, m.surnames)
+	write(nerGiven, given)
+	write(nerTitle, m.titles)
+	write(nerPlace, m.places)
+	write(nerOrg, m.orgs)
+	return bw.Flush()
+}
+
+type taggedWord struct {
+	word string
+	pos  string
+	// compound 方括号中的复合词, 其中的字已经统计过
+	compound bool
+}
+
+// parseTaggedLine 解析一行标注语料, 如 [国务院/nt 侨办/j]nt 主任/n 马/nr 朝旭/nr, 方括号中的复合词同时返回其中的词
+func parseTaggedLine(line string) ([]taggedWord, error) {
+	var words []taggedWord
+	compound := -1
+	for _, item := range strings.Fields(line) {
+		if strings.HasPrefix(item, "[") {
+			compound = len(words)
+			item = item[1:]
+		}
+		closing := ""
+		if idx := strings.LastIndex(item, "]"); idx > 0 {
+			item, closing = item[:idx], item[idx+1:]
+		}
+		idx := strings.LastIndex(item, "/")
+		if idx <= 0 {
+			return nil, errors.New("bad tagged word:" + item)
+		}
+		words = append(words, taggedWord{word: item[:idx], pos: item[idx+1:]})
+		if closing != "" && compound >= 0 {
+			var sb strings.Builder
+			for _, w := range words[compound:] {
+				sb.WriteString(w.word)
+			}
+			words = append(words, taggedWord{word: sb.String(), pos: closing, compound: true})
+			compound = -1
+		}
+	}
+	return words, nil
+}
+
+type nerCounter struct {
+	chars    map[rune]int
+	words    map[string]int
+	surnames map[string]int
+	given    map[rune]int
+	titles   map[string]int
+	// suffixes[pos][后缀] 以后缀结尾的pos词的个数, ends[后缀] 以后缀结尾的所有词的个数
+	suffixes map[string]map[string]int
+	ends     map[string]int
+}
+
+// TrainNerModel 从人民日报格式的标注语料训练模型, 每行是空格分开的"词/词性", 方括号中是复合词, 如 [国务院/nt 侨办/j]nt.
+// 人名的姓和名可以分开标注, 如 马/nr 朝旭/nr
+func TrainNerModel(r io.Reader) (*NerModel, error) {
+	c := &nerCounter{
+		chars:    map[rune]int{},
+		words:    map[string]int{},
+		surnames: map[string]int{},
+		given:    map[rune]int{},
+		titles:   map[string]int{},
+		suffixes: map[string]map[string]int{PosPlace: {}, PosOrg: {}},
+		ends:     map[string]int{},
+	}
+	scan := bufio.NewScanner(r)
+	scan.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scan.Scan() {
+		words, err := parseTaggedLine(scan.Text())
+		if err != nil {
+			return nil, err
+		}
+		c.countLine(words)
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return c.model(), nil
+}
+
+func (c *nerCounter) countLine(words []taggedWord) {
+	prev := ""
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if w.pos != PosPerson {
+			c.countWord(w)
+			prev = w.word
+			continue
+		}
+		// 连续的nr是一个人名
+		j := i + 1
+		for j < len(words) && words[j].pos == PosPerson {
+			j++
+		}
+		for _, part := range words[i:j] {
+			c.countWord(part)
+		}
+		c.countPerson(words[i:j])
+		if prev != "" {
+			c.titles[prev]++
+		}
+		prev = ""
+		i = j - 1
+	}
+}
+
+func (c *nerCounter) countWord(w taggedWord) {
+	if w.pos != PosPerson {
+		c.words[w.word]++
+	}
+	runes := []rune(w.word)
+	if !w.compound {
+		for _, r := range runes {
+			c.chars[r]++
+		}
+	}
+	for l := 1; l <= maxNerSuffix && l < len(runes); l++ {
+		suffix := string(runes[len(runes)-l:])
+		c.ends[suffix]++
+		if counts, ok := c.suffixes[w.pos]; ok {
+			counts[suffix]++
+		}
+	}
+}
+
+// countPerson 分开标注时第一个词是姓, 否则第一个字是姓, 复姓使用已经统计到的复姓
+func (c *nerCounter) countPerson(parts []taggedWord) {
+	var surname string
+	var given []rune
+	if len(parts) > 1 {
+		surname = parts[0].word
+		for _, part := range parts[1:] {
+			given = append(given, []rune(part.word)...)
+		}
+	} else {
+		runes := []rune(parts[0].word)
+		surnameLen := 1
+		if len(runes) > 3 || (len(runes) == 3 && c.surnames[string(runes[:2])] > 0) {
+			surnameLen = 2
+		}
+		surname, given = string(runes[:surnameLen]), runes[surnameLen:]
+	}
+	if utf8.RuneCountInString(surname) > 2 || len(given) > maxGivenName {
+		return
+	}
+	c.surnames[surname]++
+	for _, r := range given {
+		c.given[r]++
+	}
+}
+
+func (c *nerCounter) model() *NerModel {
+	model := newNerModel()
+	for surname, n := range c.surnames {
+		if n < nerMinCount {
+			continue
+		}
+		// 单姓按字的出现次数计算概率, 复姓按作为姓和作为其他词出现的次数计算
+		total := n + c.words[surname]
+		if r := []rune(surname); len(r) == 1 {
+			total = c.chars[r[0]]
+		}
+		model.surnames[surname] = ratio(n, total)
+	}
+	for r, n := range c.given {
+		if n >= nerMinCount {
+			model.given[r] = ratio(n, c.chars[r])
+		}
+	}
+	for title, n := range c.titles {
+		if p := ratio(n, c.words[title]); n >= nerMinCount && p >= nerMinTitle {
+			model.titles[title] = p
+		}
+	}
+	for pos, table := range map[string]map[string]float64{PosPlace: model.places, PosOrg: model.orgs} {
+		for suffix, n := range c.suffixes[pos] {
+			if p := ratio(n, c.ends[suffix]); n >= nerMinCount && p >= nerMinSuffix {
+				table[suffix] = p
+			}
+		}
+	}
+	return model
+}
+
+func ratio(n, total int) float64 {
+	if total < n {
+		total = n
+	}
+	return float64(n) / float64(total+1)
+}
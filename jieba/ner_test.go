@@ -0,0 +1,138 @@
+package jieba
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+func loadTestNerModel(t *testing.T) *NerModel {
+	model, err := LoadNerModel(os.DirFS("../dict"), NerModelName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func entityWords(tokens []*SegToken) string {
+	var entities []string
+	for _, token := range tokens {
+		switch token.Pos {
+		case PosPerson, PosPlace, PosOrg:
+			entities = append(entities, token.Word+"/"+token.Pos)
+		}
+	}
+	return strings.Join(entities, " ")
+}
+
+func TestNer(t *testing.T) {
+	handler := loadTestHandler(t)
+	model := loadTestNerModel(t)
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{"外交部发言人马朝旭", "外交部/nt 马朝旭/nr"},
+		{"孙君意", "孙君意/nr"},
+		{"程序员祝海林和朱会震", "祝海林/nr 朱会震/nr"},
+		{"记者张三报道", "张三/nr"},
+		{"欧阳娜娜和司马光去了杭州", "欧阳娜娜/nr 司马光/nr 杭州/ns"},
+		{"他住在上海市浦东新区世纪大道", "上海市/ns 浦东新区/ns 世纪大道/ns"},
+		{"上海浦东发展银行和中国科学院的王小明", "上海浦东发展银行/nt 中国科学院/nt 王小明/nr"},
+		{"我们在市里", ""},
+	}
+	for _, c := range cases {
+		// 测试词典很小, 关闭hmm时这些句子都是单字, 完全依靠实体识别
+		tokens := handler.SegParagraphWithOptions(c.input, &SegOptions{DisableHmm: true, Ner: model})
+		fmt.Println(tokens)
+		if got := entityWords(tokens); got != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.input, c.expect, got)
+		}
+		if tokens[len(tokens)-1].End != len([]rune(c.input)) {
+			t.Errorf("bad offsets %s", tokens)
+		}
+	}
+
+	// 不使用模型时不变
+	tokens := handler.SegParagraphWithOptions("孙君意", &SegOptions{DisableHmm: true})
+	if len(tokens) != 3 {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
+
+func TestTrainNerModel(t *testing.T) {
+	corpus := `19980101-01-001-001/m 外交部/nt 发言人/n 马/nr 朝旭/nr 说/v
+记者/n 张/nr 小明/nr 报道/v ，/w [北京/ns 大学/n]nt 教授/n 王/nr 伟/nr 在/p 海淀区/ns 讲课/v
+记者/n 李伟/nr 和/c 欧阳/nr 娜娜/nr 到/v 朝阳区/ns 和/c 西城区/ns ，/w [中国/ns 银行/n]nt 和/c [建设/vn 银行/n]nt
+他/r 在/p 北京/ns 市区/n 工作/v ，/w 王/nr 明/nr 和/c 欧阳娜娜/nr 也/d 在/p`
+	model, err := TrainNerModel(strings.NewReader(corpus))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = model.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(buf.String())
+
+	if p := model.surnames["王"]; p < 0.5 {
+		t.Errorf("bad surname prob %v", p)
+	}
+	if _, ok := model.surnames["欧阳"]; !ok {
+		t.Error("expect double surname")
+	}
+	if p := model.given['伟']; p <= 0 {
+		t.Errorf("bad given prob %v", p)
+	}
+	if _, ok := model.titles["记者"]; !ok {
+		t.Error("expect title")
+	}
+	if _, ok := model.places["区"]; !ok {
+		t.Error("expect place suffix")
+	}
+	if _, ok := model.orgs["银行"]; !ok {
+		t.Error("expect org suffix")
+	}
+
+	// 保存后重新加载
+	loaded, err := LoadNerModel(os.DirFS(t.TempDir()), NerModelName)
+	if err == nil || loaded != nil {
+		t.Error("expect not found")
+	}
+	dir := t.TempDir()
+	if err = os.WriteFile(dir+"/"+NerModelName, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = LoadNerModel(os.DirFS(dir), NerModelName); err != nil {
+		t.Fatal(err)
+	}
+	var saved bytes.Buffer
+	if err = loaded.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.String() != buf.String() {
+		t.Errorf("reload differs:\n%s", saved.String())
+	}
+
+	if _, err = TrainNerModel(strings.NewReader("没有词性")); err == nil {
+		t.Error("expect error")
+	}
+
+	// #开头的是注释, 宽松模式跳过格式错误的行
+	fsys := fstest.MapFS{NerModelName: &fstest.MapFile{Data: []byte("# comment\nsurname 王 0.9\nbad line\n")}}
+	if _, err = LoadNerModel(fsys, NerModelName); err == nil || err.Error() != "ner.txt:3: bad ner items:bad line" {
+		t.Errorf("expect line error, got %v", err)
+	}
+	opts := &dictload.Options{Lenient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if loaded, err = LoadNerModelWithOptions(fsys, NerModelName, opts); err != nil || loaded.surnames["王"] != 0.9 {
+		t.Errorf("bad lenient load %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package jieba
+
+// offsetTable 记录每个rune在原始输入中的字节偏移和utf16偏移, 用于把token的rune偏移转换成字节偏移和utf16偏移
+type offsetTable struct {
+	bytes []int
+	utf16 []int
+	// nextUtf16 下一个rune的utf16偏移
+	nextUtf16 int
+}
+
+func newOffsetTable(capacity int) *offsetTable {
+	return &offsetTable{
+		bytes: make([]int, 0, capacity+1),
+		utf16: make([]int, 0, capacity+1),
+	}
+}
+
+// add 添加一个rune, bytePos是它在原始输入中的字节偏移
+func (ot *offsetTable) add(bytePos int, r rune) {
+	ot.bytes = append(ot.bytes, bytePos)
+	ot.utf16 = append(ot.utf16, ot.nextUtf16)
+	if r >= 0x10000 {
+		ot.nextUtf16 += 2
+	} else {
+		ot.nextUtf16++
+	}
+}
+
+// finish 添加结束位置, byteEnd是最后一个rune之后的字节偏移
+func (ot *offsetTable) finish(byteEnd int) {
+	ot.bytes = append(ot.bytes, byteEnd)
+	ot.utf16 = append(ot.utf16, ot.nextUtf16)
+}
+
+// reset 清空, 之后的rune从utf16偏移nextUtf16开始
+func (ot *offsetTable) reset(nextUtf16 int) {
+	ot.bytes = ot.bytes[:0]
+	ot.utf16 = ot.utf16[:0]
+	ot.nextUtf16 = nextUtf16
+}
+
+// fill 填充token的字节偏移、utf16偏移和原始文本, base是表中第一个rune的rune偏移, text是对应的原始输入
+func (ot *offsetTable) fill(tokens []*SegToken, base int, text string) {
+	byteBase := ot.bytes[0]
+	for _, token := range tokens {
+		token.ByteStart = ot.bytes[token.Start-base]
+		token.ByteEnd = ot.bytes[token.End-base]
+		token.Utf16Start = ot.utf16[token.Start-base]
+		token.Utf16End = ot.utf16[token.End-base]
+		token.Text = text[token.ByteStart-byteBase : token.ByteEnd-byteBase]
+	}
+}
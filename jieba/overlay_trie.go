@@ -0,0 +1,279 @@
+package jieba
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"strings"
+	"sync"
+)
+
+// layer 可以作为覆盖层基础的词典, matchForward和eachWord需要在rlock和返回的解锁函数之间调用
+type layer interface {
+	Trie
+	matchForward(from int, statement []rune) []*segTokenInternal
+	// eachWord 遍历所有词, freq是对数概率
+	eachWord(f func(word []rune, freq float64, pos string))
+	rlock() func()
+	minWeight() float64
+}
+
+func (root *trieNodeHolder) rlock() func() {
+	root.lock.RLock()
+	return root.lock.RUnlock
+}
+
+func (root *trieNodeHolder) minWeight() float64 {
+	return root.minFreq
+}
+
+func (root *trieNodeHolder) eachWord(f func(word []rune, freq float64, pos string)) {
+	for word, nd := range root.shortWord {
+		f([]rune(word), nd.freq, nd.pos)
+	}
+}
+
+func (dt *datTrie) rlock() func() {
+	return func() {}
+}
+
+func (dt *datTrie) minWeight() float64 {
+	return dt.minFreq
+}
+
+func (dt *datTrie) eachWord(f func(word []rune, freq float64, pos string)) {
+	for i, word := range dt.da.Keys() {
+		f(word, dt.wordFreq[i], dt.posTable[dt.wordPos[i]])
+	}
+}
+
+// overlayTrie 在共享的基础词典上叠加一个小的覆盖层, 记录新增、删除的词和修改的词频, 查询时同时查两层.
+// 修改只影响覆盖层, 基础词典可以被多个覆盖层共享, 创建覆盖层的开销只与覆盖层的大小有关
+type overlayTrie struct {
+	base layer
+	// root 新增或修改的词, freq是对数概率, 同基础词典使用基础词典的total
+	root *trieNode
+	// words 覆盖层中的词, deleted 从基础词典删除的词
+	words   map[string]*trieNode
+	deleted map[string]struct{}
+	lock    sync.RWMutex
+}
+
+func newOverlayTrie(base layer) *overlayTrie {
+	return &overlayTrie{
+		base:    base,
+		root:    &trieNode{},
+		words:   map[string]*trieNode{},
+		deleted: map[string]struct{}{},
+	}
+}
+
+func (o *overlayTrie) rlock() func() {
+	o.lock.RLock()
+	unlock := o.base.rlock()
+	return func() {
+		unlock()
+		o.lock.RUnlock()
+	}
+}
+
+func (o *overlayTrie) minWeight() float64 {
+	return o.base.minWeight()
+}
+
+// matchForward 合并两层的候选词, 长度相同时使用覆盖层的词频, 去掉删除的词
+func (o *overlayTrie) matchForward(from int, statement []rune) []*segTokenInternal {
+	var ret []*segTokenInternal
+	overlay := o.overlayForward(from, statement)
+	k := 0
+	for _, seg := range o.base.matchForward(from, statement) {
+		if seg.unknown {
+			break
+		}
+		l := seg.len()
+		for k < len(overlay) && overlay[k].len() < l {
+			ret = append(ret, overlay[k])
+			k++
+		}
+		if k < len(overlay) && overlay[k].len() == l {
+			continue
+		}
+		if len(o.deleted) > 0 {
+			if _, ok := o.deleted[string(statement[:l])]; ok {
+				continue
+			}
+		}
+		ret = append(ret, seg)
+	}
+	ret = append(ret, overlay[k:]...)
+	if ret == nil {
+		ret = append(ret, unknownSeg(from, o.minWeight()))
+	}
+	return ret
+}
+
+// eachWord 基础词典中没有被覆盖或删除的词, 以及覆盖层的词
+func (o *overlayTrie) eachWord(f func(word []rune, freq float64, pos string)) {
+	o.base.eachWord(func(word []rune, freq float64, pos string) {
+		w := string(word)
+		if _, ok := o.words[w]; ok {
+			return
+		}
+		if _, ok := o.deleted[w]; ok {
+			return
+		}
+		f(word, freq, pos)
+	})
+	for word, nd := range o.words {
+		f([]rune(word), nd.freq, nd.pos)
+	}
+}
+
+// compactBase 覆盖层为空时返回基础词典
+func (o *overlayTrie) compactBase() (*datTrie, bool) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	dt, ok := o.base.(*datTrie)
+	return dt, ok && len(o.words) == 0 && len(o.deleted) == 0
+}
+
+func (o *overlayTrie) overlayForward(from int, statement []rune) []*segTokenInternal {
+	var ret []*segTokenInternal
+	p := o.root
+	for i, v := range statement {
+		if p = p.children[v]; p == nil {
+			break
+		}
+		if p.wordEnd {
+			ret = append(ret, &segTokenInternal{Segment: &Segment{Start: from, End: from + i + 1}, weight: p.freq})
+		}
+	}
+	return ret
+}
+
+func (o *overlayTrie) Match(sentence []rune) []*Segment {
+	defer o.rlock()()
+	return bestRoute(sentence, o.matchForward)
+}
+
+func (o *overlayTrie) MatchAll(sentence []rune) [][]*Segment {
+	defer o.rlock()()
+	return allMatches(sentence, o.matchForward)
+}
+
+// lookup 覆盖层中的词, found为false时需要查基础词典
+func (o *overlayTrie) lookup(word string) (nd *trieNode, found bool) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	if nd, ok := o.words[word]; ok {
+		return nd, true
+	}
+	_, deleted := o.deleted[word]
+	return nil, deleted
+}
+
+func (o *overlayTrie) ExistShortWord(word string) bool {
+	if nd, found := o.lookup(word); found {
+		return nd != nil
+	}
+	return o.base.ExistShortWord(word)
+}
+
+func (o *overlayTrie) Pos(word string) (string, bool) {
+	if nd, found := o.lookup(word); found {
+		if nd == nil {
+			return "", false
+		}
+		return nd.pos, true
+	}
+	return o.base.Pos(word)
+}
+
+func (o *overlayTrie) Freq(word string) (float64, bool) {
+	if nd, found := o.lookup(word); found {
+		if nd == nil {
+			return 0, false
+		}
+		return expFreq(nd.freq, o.Total()), true
+	}
+	return o.base.Freq(word)
+}
+
+func (o *overlayTrie) Total() float64 {
+	return o.base.Total()
+}
+
+// AddWord 只添加到覆盖层, 同用户词典不计入total
+func (o *overlayTrie) AddWord(word string, freq float64, pos string) {
+	word = strings.ToLower(word)
+	freq = math.Log(freq / o.Total())
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	delete(o.deleted, word)
+	p := o.root
+	for _, r := range word {
+		if p.children == nil {
+			p.children = map[rune]*trieNode{}
+		}
+		child := p.children[r]
+		if child == nil {
+			child = &trieNode{}
+			p.children[r] = child
+		}
+		p = child
+	}
+	p.wordEnd, p.freq, p.pos = true, freq, pos
+	o.words[word] = p
+}
+
+// DelWord 从覆盖层删除, 基础词典中有该词时记录为删除, 不修改基础词典
+func (o *overlayTrie) DelWord(word string) bool {
+	word = strings.ToLower(word)
+	inBase := o.base.ExistShortWord(word)
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	nd, inOverlay := o.words[word]
+	if inOverlay {
+		nd.wordEnd = false
+		delete(o.words, word)
+	}
+	_, deleted := o.deleted[word]
+	if inBase {
+		o.deleted[word] = struct{}{}
+	}
+	return inOverlay || (inBase && !deleted)
+}
+
+// Overlay 返回与h共享词典和模型的新handler, 新handler的AddWord、DelWord只修改自己的覆盖层, 不影响h和h的其他覆盖层,
+// 分词时同时查询覆盖层和h的词典. 创建的开销与h的词典大小无关, 适合多租户各自的词汇. h的词典是只读的预编译词典时也可以使用
+func (h *SegmentHandler) Overlay() (*SegmentHandler, error) {
+	base, ok := h.dict.(layer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported dict type %T", h.dict)
+	}
+	return &SegmentHandler{
+		dict:     newOverlayTrie(base),
+		hmm:      h.hmm,
+		posHmm:   h.posHmm,
+		loadOpts: h.loadOpts,
+	}, nil
+}
+
+// LoadOverlay 创建覆盖层并从fsys加载names中的词典, 格式同用户词典, 见dictHeader, 优先级无效.
+// 没有词频时使用SuggestFreq计算, 词频为0表示删除h的词典中的词. 格式错误的行按创建h时的dictload.Options处理
+func (h *SegmentHandler) LoadOverlay(fsys fs.FS, names ...string) (*SegmentHandler, error) {
+	overlay, err := h.Overlay()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		file, err := parseDictFile(fsys, name, false, overlay.loadOpts)
+		if err != nil {
+			return nil, err
+		}
+		if err = overlay.applyDictFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return overlay, nil
+}
@@ -0,0 +1,72 @@
+package jieba
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlay(t *testing.T) {
+	handler := loadTestHandler(t)
+	compact, err := handler.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"tenant.dict": &fstest.MapFile{Data: []byte("南京市长 99999 nr\n长江大桥 0\n杭研大厦\n")}}
+	sentence := "南京市长江大桥和杭研大厦"
+	seg := func(h *SegmentHandler) string {
+		return strings.Join(tokenWords(h.SegParagraphWithOptions(sentence, &SegOptions{DisableHmm: true})), "/")
+	}
+
+	for _, base := range []*SegmentHandler{handler, compact} {
+		expect := seg(base)
+		tenant, err := base.LoadOverlay(fsys, "tenant.dict")
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := base.Overlay()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println(seg(base), seg(tenant))
+		if got := seg(tenant); got != "南京市长/江大桥/和/杭研大厦" {
+			t.Errorf("bad overlay seg %s", got)
+		}
+		// 基础词典和其他覆盖层不受影响
+		if seg(base) != expect || seg(other) != expect {
+			t.Errorf("base changed: %s %s", seg(base), seg(other))
+		}
+
+		if pos, _ := tenant.dict.Pos("南京市长"); pos != "nr" {
+			t.Errorf("bad pos %s", pos)
+		}
+		if tenant.dict.ExistShortWord("长江大桥") || !base.dict.ExistShortWord("长江大桥") {
+			t.Error("bad deleted word")
+		}
+		if freq, _ := tenant.Freq("大桥"); freq <= 0 {
+			t.Errorf("bad base freq %v", freq)
+		}
+		// 覆盖层的覆盖层
+		nested, err := tenant.Overlay()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = nested.AddWord("长江大桥", 99999, "ns"); err != nil {
+			t.Fatal(err)
+		}
+		if got := seg(nested); got != "南京市/长江大桥/和/杭研大厦" {
+			t.Errorf("bad nested seg %s", got)
+		}
+
+		if ok, _ := tenant.DelWord("杭研大厦"); !ok {
+			t.Error("expect deleted")
+		}
+		if ok, _ := tenant.DelWord("长江大桥"); ok {
+			t.Error("expect already deleted")
+		}
+		if got := seg(tenant); got != "南京市长/江大桥/和/杭研/大厦" {
+			t.Errorf("bad seg after delete %s", got)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package jieba
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+const (
+	PosModelDirName  = "pos"
+	posProbStartName = "prob_start.txt"
+	posProbTransName = "prob_trans.txt"
+	posProbEmitName  = "prob_emit.txt"
+	posCharStateName = "char_state.txt"
+)
+
+const (
+	PosEnglish  = "eng"
+	PosNumber   = "m"
+	PosQuantity = "mq"
+	PosTime     = "t"
+	PosUnknown  = "x"
+)
+
+var reNum = regexp.MustCompile(`^[.0-9]+`)
+var reEng = regexp.MustCompile(`^[a-zA-Z0-9]+`)
+
+// posState 联合状态, 分词状态(BMES) x 词性, 与python jieba posseg一致
+type posState struct {
+	bmes rune
+	pos  string
+}
+
+type wordTag struct {
+	word string
+	pos  string
+}
+
+// posHmmSeg 基于BMES x 词性联合状态的hmm, 给HmmSeg切分出的未登录词标注词性, 不改变切分
+type posHmmSeg interface {
+	TagWords(words []string) []*wordTag
+}
+
+type posHmmImpl struct {
+	states    []posState
+	stateIds  map[posState]int
+	start     []float64
+	trans     []map[int]float64
+	emits     []map[rune]float64
+	charState map[rune][]int
+	allStates []int
+}
+
+// newPosHmmSeg 从dirPath加载词性hmm模型, 模型目录不存在时返回nil
+func newPosHmmSeg(fsys fs.FS, dirPath string, opts *dictload.Options) (posHmmSeg, error) {
+	if _, err := fs.Stat(fsys, dirPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	hmm := &posHmmImpl{
+		stateIds:  map[posState]int{},
+		charState: map[rune][]int{},
+	}
+	if err := hmm.loadStart(fsys, opts, path.Join(dirPath, posProbStartName)); err != nil {
+		return nil, err
+	}
+	if err := hmm.loadTrans(fsys, opts, path.Join(dirPath, posProbTransName)); err != nil {
+		return nil, err
+	}
+	if err := hmm.loadEmit(fsys, opts, path.Join(dirPath, posProbEmitName)); err != nil {
+		return nil, err
+	}
+	if err := hmm.loadCharState(fsys, opts, path.Join(dirPath, posCharStateName)); err != nil {
+		return nil, err
+	}
+	// 模型文件都是空的时同没有模型, 未登录词按规则标注词性
+	if len(hmm.states) == 0 {
+		return nil, nil
+	}
+	for i := range hmm.states {
+		hmm.allStates = append(hmm.allStates, i)
+	}
+	return hmm, nil
+}
+
+func parsePosState(s string) (posState, error) {
+	items := strings.SplitN(s, "/", 2)
+	bmes := []rune(items[0])
+	if len(items) != 2 || len(bmes) != 1 || !slices.Contains(states, bmes[0]) {
+		return posState{}, errors.New("bad pos state:" + s)
+	}
+	return posState{bmes: bmes[0], pos: items[1]}, nil
+}
+
+func (hmm *posHmmImpl) stateId(s string) (int, error) {
+	ps, err := parsePosState(s)
+	if err != nil {
+		return 0, err
+	}
+	if id, ok := hmm.stateIds[ps]; ok {
+		return id, nil
+	}
+	id := len(hmm.states)
+	hmm.states = append(hmm.states, ps)
+	hmm.stateIds[ps] = id
+	hmm.start = append(hmm.start, minFloat)
+	hmm.trans = append(hmm.trans, map[int]float64{})
+	hmm.emits = append(hmm.emits, map[rune]float64{})
+	return id, nil
+}
+
+// scanModelFile 逐行读取模型文件, 以空白分隔各列, 跳过空行
+func scanModelFile(fsys fs.FS, fp string, opts *dictload.Options, accept func(items []string) error) error {
+	_, err := dictload.Load(fsys, fp, opts, func(line string) error {
+		items := strings.Fields(line)
+		if len(items) == 0 {
+			return dictload.ErrSkip
+		}
+		return accept(items)
+	})
+	return err
+}
+
+// loadStart 每行: 状态/词性 概率, 例如 B/n -4.2
+func (hmm *posHmmImpl) loadStart(fsys fs.FS, opts *dictload.Options, fp string) error {
+	return scanModelFile(fsys, fp, opts, func(items []string) error {
+		if len(items) != 2 {
+			return errors.New("bad start items:" + strings.Join(items, " "))
+		}
+		id, err := hmm.stateId(items[0])
+		if err != nil {
+			return err
+		}
+		hmm.start[id], err = strconv.ParseFloat(items[1], 64)
+		return err
+	})
+}
+
+// loadTrans 每行: 前状态/词性 后状态/词性 概率
+func (hmm *posHmmImpl) loadTrans(fsys fs.FS, opts *dictload.Options, fp string) error {
+	return scanModelFile(fsys, fp, opts, func(items []string) error {
+		if len(items) != 3 {
+			return errors.New("bad trans items:" + strings.Join(items, " "))
+		}
+		from, err := hmm.stateId(items[0])
+		if err != nil {
+			return err
+		}
+		to, err := hmm.stateId(items[1])
+		if err != nil {
+			return err
+		}
+		hmm.trans[from][to], err = strconv.ParseFloat(items[2], 64)
+		return err
+	})
+}
+
+// loadEmit 格式同prob_emit.txt, 单独一列的行是状态/词性, 其后每行: 字 概率
+func (hmm *posHmmImpl) loadEmit(fsys fs.FS, opts *dictload.Options, fp string) error {
+	var values map[rune]float64
+	return scanModelFile(fsys, fp, opts, func(items []string) error {
+		if len(items) == 1 {
+			id, err := hmm.stateId(items[0])
+			if err != nil {
+				return err
+			}
+			values = hmm.emits[id]
+			return nil
+		}
+		if values == nil {
+			return errors.New("emit without state:" + strings.Join(items, " "))
+		}
+		if len(items) != 2 {
+			return errors.New("bad emit items:" + strings.Join(items, " "))
+		}
+		rvs := []rune(items[0])
+		var err error
+		values[rvs[0]], err = strconv.ParseFloat(items[1], 64)
+		return err
+	})
+}
+
+// loadCharState 每行: 字 状态/词性 状态/词性 ..., 限定每个字可能的状态
+func (hmm *posHmmImpl) loadCharState(fsys fs.FS, opts *dictload.Options, fp string) error {
+	return scanModelFile(fsys, fp, opts, func(items []string) error {
+		rvs := []rune(items[0])
+		ids := make([]int, 0, len(items)-1)
+		for _, item := range items[1:] {
+			id, err := hmm.stateId(item)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		hmm.charState[rvs[0]] = ids
+		return nil
+	})
+}
+
+// TagWords 连续的汉字词按切分限定每个字的BMES状态, 用viterbi求出最优的词性, 词的词性为第一个字的词性;
+// 其他词按规则标注
+func (hmm *posHmmImpl) TagWords(words []string) []*wordTag {
+	tokens := make([]*wordTag, 0, len(words))
+	var chinese, bmes []rune
+	from := 0
+	flush := func() {
+		if len(chinese) == 0 {
+			return
+		}
+		route, _ := hmm.route(chinese, bmes)
+		i := 0
+		for _, tag := range tokens[from:] {
+			if route != nil {
+				tag.pos = route[i].pos
+			}
+			i += utf8.RuneCountInString(tag.word)
+		}
+		chinese, bmes = chinese[:0], bmes[:0]
+	}
+	for _, word := range words {
+		runes := []rune(word)
+		if len(runes) == 0 || !isCjk(runes[0]) {
+			flush()
+			tokens = append(tokens, &wordTag{word: word, pos: otherWordPos(word)})
+			continue
+		}
+		if len(chinese) == 0 {
+			from = len(tokens)
+		}
+		tokens = append(tokens, &wordTag{word: word, pos: PosUnknown})
+		chinese = append(chinese, runes...)
+		bmes = appendBmes(bmes, len(runes))
+	}
+	flush()
+	return tokens
+}
+
+// appendBmes 长度为l的词每个字的状态
+func appendBmes(bmes []rune, l int) []rune {
+	if l == 1 {
+		return append(bmes, 'S')
+	}
+	bmes = append(bmes, 'B')
+	for i := 1; i < l-1; i++ {
+		bmes = append(bmes, 'M')
+	}
+	return append(bmes, 'E')
+}
+
+func (hmm *posHmmImpl) obsStates(r rune) []int {
+	if ids, ok := hmm.charState[r]; ok {
+		return ids
+	}
+	return hmm.allStates
+}
+
+func (hmm *posHmmImpl) emit(state int, r rune) float64 {
+	if p, ok := hmm.emits[state][r]; ok {
+		return p
+	}
+	return minFloat
+}
+
+// route viterbi求出的最优联合状态序列, 以及每个位置上该状态的累计对数概率, 第t个字只能是BMES状态为bmes[t]的状态.
+// 模型没有这样的状态时返回nil
+func (hmm *posHmmImpl) route(chinese []rune, bmes []rune) ([]posState, []float64) {
+	l := len(chinese)
+	if l == 0 || len(hmm.states) == 0 {
+		return nil, nil
+	}
+	// allowed 依次从候选中过滤出状态为bmes[t]的, 都没有时使用下一组候选
+	allowed := func(t int, candidates ...[]int) []int {
+		for _, ids := range candidates {
+			var ret []int
+			for _, y := range ids {
+				if hmm.states[y].bmes == bmes[t] {
+					ret = append(ret, y)
+				}
+			}
+			if len(ret) > 0 {
+				return ret
+			}
+		}
+		return nil
+	}
+	v := make([]map[int]float64, l)
+	memPath := make([]map[int]int, l)
+
+	v[0] = map[int]float64{}
+	memPath[0] = map[int]int{}
+	for _, y := range allowed(0, hmm.obsStates(chinese[0]), hmm.allStates) {
+		v[0][y] = hmm.start[y] + hmm.emit(y, chinese[0])
+		memPath[0][y] = -1
+	}
+	if len(v[0]) == 0 {
+		return nil, nil
+	}
+
+	for t := 1; t < l; t++ {
+		v[t] = map[int]float64{}
+		memPath[t] = map[int]int{}
+
+		var prevStates []int
+		expectNext := map[int]struct{}{}
+		for x := range memPath[t-1] {
+			if len(hmm.trans[x]) == 0 {
+				continue
+			}
+			prevStates = append(prevStates, x)
+			for y := range hmm.trans[x] {
+				expectNext[y] = struct{}{}
+			}
+		}
+		if len(prevStates) == 0 {
+			for x := range memPath[t-1] {
+				prevStates = append(prevStates, x)
+			}
+		}
+		slices.Sort(prevStates)
+
+		var obsStates, nextStates []int
+		for _, y := range hmm.obsStates(chinese[t]) {
+			if _, ok := expectNext[y]; ok {
+				obsStates = append(obsStates, y)
+			}
+		}
+		for y := range expectNext {
+			nextStates = append(nextStates, y)
+		}
+		slices.Sort(nextStates)
+
+		for _, y := range allowed(t, obsStates, nextStates, hmm.allStates) {
+			best := math.Inf(-1)
+			bestPrev := -1
+			em := hmm.emit(y, chinese[t])
+			for _, y0 := range prevStates {
+				tranP, ok := hmm.trans[y0][y]
+				if !ok {
+					tranP = minFloat
+				}
+				prob := v[t-1][y0] + tranP + em
+				if bestPrev == -1 || prob > best {
+					best = prob
+					bestPrev = y0
+				}
+			}
+			v[t][y] = best
+			memPath[t][y] = bestPrev
+		}
+		if len(v[t]) == 0 {
+			return nil, nil
+		}
+	}
+
+	last := -1
+	for y, prob := range v[l-1] {
+		if last == -1 || prob > v[l-1][last] || (prob == v[l-1][last] && y > last) {
+			last = y
+		}
+	}
+
+	route := make([]posState, l)
+	probs := make([]float64, l)
+	for i := l - 1; i >= 0; i-- {
+		route[i] = hmm.states[last]
+		probs[i] = v[i][last]
+		last = memPath[i][last]
+	}
+	return route, probs
+}
+
+// otherWordPos 非汉字片段的词性: 数字为m, 英文字母数字为eng, 其他为x
+func otherWordPos(word string) string {
+	if reNum.MatchString(word) {
+		return PosNumber
+	}
+	if reEng.MatchString(word) {
+		return PosEnglish
+	}
+	return PosUnknown
+}
@@ -0,0 +1,315 @@
+package jieba
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeNumber   = "number"
+	TypeQuantity = "quantity"
+)
+
+// QuantityRecognizer 识别数词和数量词, 合并阿拉伯数字、汉字数字、小数、百分数以及数字+量词/单位, 并给出数值.
+// 如 两块五(2.5) 二〇二四年(2024) 18.90元(18.9) 60周年(60) 百分之五十(50) 1.5万(15000) 第三届(3).
+// 单独一个汉字数字不识别, 避免破坏"统一"这样的词
+var QuantityRecognizer Recognizer = quantityRecognizer{}
+
+var chineseDigits = map[rune]int{
+	'零': 0, '〇': 0,
+	'一': 1, '壹': 1,
+	'二': 2, '贰': 2, '两': 2,
+	'三': 3, '叁': 3,
+	'四': 4, '肆': 4,
+	'五': 5, '伍': 5,
+	'六': 6, '陆': 6,
+	'七': 7, '柒': 7,
+	'八': 8, '捌': 8,
+	'九': 9, '玖': 9,
+}
+
+var chineseUnits = map[rune]float64{
+	'十': 10, '拾': 10,
+	'百': 100, '佰': 100,
+	'千': 1000, '仟': 1000,
+	'万': 1e4,
+	'亿': 1e8,
+}
+
+// quantityUnits 量词和单位, 匹配时使用最长的
+var quantityUnits = []string{
+	"元", "块", "角", "毛", "美元", "欧元", "日元", "英镑", "港元", "港币",
+	"斤", "公斤", "千克", "克", "吨", "磅",
+	"米", "公里", "千米", "厘米", "毫米", "英里", "平方米", "平米", "亩", "升", "毫升",
+	"年", "个月", "月", "日", "号", "天", "周", "周年", "小时", "分钟", "秒", "秒钟", "岁", "世纪", "星期",
+	"个", "只", "件", "套", "本", "张", "条", "辆", "台", "部", "位", "名", "家", "次", "遍", "趟", "场", "份",
+	"双", "对", "瓶", "杯", "碗", "盒", "箱", "包", "袋", "把", "支", "根", "颗", "粒", "片", "篇", "首", "座",
+	"栋", "层", "间", "户", "口", "头", "匹", "架", "艘", "届", "期", "项", "章", "节", "页", "行", "倍", "度",
+	"点", "分",
+}
+
+// ambiguousUnits 和"一"组成常用词的量词, 如 一本正经 一部分 一点 一口气, "一"后面的这些量词不识别
+var ambiguousUnits = map[string]bool{
+	"本": true, "部": true, "家": true, "口": true, "头": true, "点": true, "度": true,
+	"行": true, "节": true, "把": true, "片": true, "分": true,
+}
+
+var maxUnitLen int
+
+func init() {
+	for _, unit := range quantityUnits {
+		if l := len([]rune(unit)); l > maxUnitLen {
+			maxUnitLen = l
+		}
+	}
+}
+
+type quantityRecognizer struct{}
+
+func (quantityRecognizer) Recognize(text []rune, start int) (int, string) {
+	length, typ, _ := scanQuantity(text, start)
+	return length, typ
+}
+
+func (quantityRecognizer) Value(word []rune) (float64, bool) {
+	length, _, value := scanQuantity(word, 0)
+	return value, length == len(word)
+}
+
+func matchUnit(text []rune, pos int) string {
+	for l := maxUnitLen; l > 0; l-- {
+		if pos+l > len(text) {
+			continue
+		}
+		unit := string(text[pos : pos+l])
+		for _, u := range quantityUnits {
+			if u == unit {
+				return unit
+			}
+		}
+	}
+	return ""
+}
+
+func scanQuantity(text []rune, start int) (int, string, float64) {
+	pos := start
+	ordinal := text[pos] == '第'
+	if ordinal {
+		pos++
+	}
+	percent := false
+	if hasPrefix(text, pos, "百分之") {
+		percent = true
+		pos += 3
+	}
+
+	var n int
+	var value float64
+	arabic := pos < len(text) && isDigit(text[pos])
+	if arabic {
+		if start > 0 && (isAsciiAlnum(text[start-1]) || text[start-1] == '.') {
+			return 0, "", 0
+		}
+		n, value = scanArabic(text, pos)
+	} else {
+		n, value = scanChinese(text, pos)
+	}
+	if n == 0 {
+		return 0, "", 0
+	}
+	// 五千米: 千米是单位
+	if !arabic && n > 1 && len([]rune(matchUnit(text, pos+n-1))) > 1 {
+		if v, ok := parseChinese(text[pos : pos+n-1]); ok {
+			n, value = n-1, v
+		}
+	}
+	single := !arabic && n == 1
+	numeral := string(text[pos : pos+n])
+	pos += n
+
+	if arabic && pos < len(text) && isEnglish(text[pos]) {
+		return 0, "", 0
+	}
+	if percent {
+		return pos - start, TypeQuantity, value
+	}
+	if arabic && pos < len(text) && (text[pos] == '%' || text[pos] == '‰') {
+		return pos + 1 - start, TypeQuantity, value
+	}
+
+	unit := matchUnit(text, pos)
+	if unit != "" && ambiguousUnits[unit] && (numeral == "一" || (unit == "分" && !arabic)) {
+		unit = ""
+	}
+	if unit == "" {
+		if single && !ordinal {
+			return 0, "", 0
+		}
+		return pos - start, TypeNumber, value
+	}
+	pos += len([]rune(unit))
+	if unit == "块" || unit == "元" {
+		var tail int
+		tail, value = scanMoneyTail(text, pos, value)
+		pos += tail
+	}
+	return pos - start, TypeQuantity, value
+}
+
+// scanMoneyTail 口语的钱数, 块/元后面的角和分, 如 两块五 三块五毛八
+func scanMoneyTail(text []rune, pos int, value float64) (int, float64) {
+	start := pos
+	digit := func(i int) (int, bool) {
+		if i >= len(text) {
+			return 0, false
+		}
+		if isDigit(text[i]) {
+			return int(text[i] - '0'), true
+		}
+		d, ok := chineseDigits[text[i]]
+		return d, ok && text[i] != '两'
+	}
+	jiao, ok := digit(pos)
+	if !ok {
+		return 0, value
+	}
+	next := pos + 1
+	if next < len(text) && (text[next] == '毛' || text[next] == '角') {
+		next++
+		if fen, ok := digit(next); ok {
+			value += float64(fen) / 100
+			next++
+			if next < len(text) && text[next] == '分' {
+				next++
+			}
+		}
+	} else if unit := matchUnit(text, next); unit != "" {
+		// 三块一斤: 一斤是另一个数量
+		return 0, value
+	}
+	value += float64(jiao) / 10
+	return next - start, value
+}
+
+// scanArabic 阿拉伯数字, 可以有千分位和小数, 后面可以跟万、亿等
+func scanArabic(text []rune, pos int) (int, float64) {
+	start := pos
+	for pos < len(text) && isDigit(text[pos]) {
+		pos++
+	}
+	// 千分位 1,234,567
+	for pos+3 < len(text) && text[pos] == ',' && isDigit(text[pos+1]) && isDigit(text[pos+2]) && isDigit(text[pos+3]) &&
+		(pos+4 == len(text) || !isDigit(text[pos+4])) {
+		pos += 4
+	}
+	if pos+1 < len(text) && text[pos] == '.' && isDigit(text[pos+1]) {
+		pos++
+		for pos < len(text) && isDigit(text[pos]) {
+			pos++
+		}
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(string(text[start:pos]), ",", ""), 64)
+	if err != nil {
+		return 0, 0
+	}
+	if pos < len(text) && len([]rune(matchUnit(text, pos))) <= 1 {
+		if unit, ok := chineseUnits[text[pos]]; ok && unit > 10 {
+			value *= unit
+			pos++
+		}
+	}
+	return pos - start, value
+}
+
+// scanChinese 汉字数字, 返回最长的合法数字
+func scanChinese(text []rune, pos int) (int, float64) {
+	end := pos
+	for end < len(text) {
+		_, digit := chineseDigits[text[end]]
+		_, unit := chineseUnits[text[end]]
+		if !digit && !unit {
+			break
+		}
+		end++
+	}
+	for l := end - pos; l > 0; l-- {
+		if value, ok := parseChinese(text[pos : pos+l]); ok {
+			return l, value
+		}
+	}
+	return 0, 0
+}
+
+// parseChinese 解析汉字数字, 如 三百二十五 一万零五 两千五(2500) 二〇二四(2024)
+func parseChinese(word []rune) (float64, bool) {
+	hasUnit := false
+	for _, r := range word {
+		if _, ok := chineseUnits[r]; ok {
+			hasUnit = true
+			break
+		}
+	}
+	if !hasUnit {
+		// 逐位读的数字, 如 二〇二四, 至少3位, 避免"三五成群"这样的词
+		if len(word) == 2 {
+			return 0, false
+		}
+		value := 0.0
+		for _, r := range word {
+			if r == '两' && len(word) > 1 {
+				return 0, false
+			}
+			value = value*10 + float64(chineseDigits[r])
+		}
+		return value, true
+	}
+
+	var total, section, number, lastUnit float64
+	prevDigit, zero := false, false
+	for i, r := range word {
+		if d, ok := chineseDigits[r]; ok {
+			if d == 0 {
+				zero, prevDigit, number = true, false, 0
+				continue
+			}
+			if prevDigit {
+				return 0, false
+			}
+			number = float64(d)
+			prevDigit = true
+			continue
+		}
+		unit := chineseUnits[r]
+		switch {
+		case unit == 10 && !prevDigit && (i == 0 || zero):
+			// 十五 一百零十
+			number = 1
+		case unit < 1e4 && !prevDigit:
+			return 0, false
+		case unit >= 1e4 && total+section+number == 0:
+			return 0, false
+		}
+		if unit < 1e4 {
+			if lastUnit > 0 && lastUnit < 1e4 && unit >= lastUnit {
+				return 0, false
+			}
+			section += number * unit
+		} else if unit == 1e8 {
+			total = (total + section + number) * unit
+			section = 0
+		} else {
+			total += (section + number) * unit
+			section = 0
+		}
+		lastUnit = unit
+		number = 0
+		prevDigit, zero = false, false
+	}
+	if prevDigit && !zero && lastUnit > 10 {
+		// 两千五 三万五
+		number *= lastUnit / 10
+	}
+	value := total + section + number
+	return value, !math.IsNaN(value) && value > 0
+}
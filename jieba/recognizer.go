@@ -0,0 +1,509 @@
+package jieba
+
+import (
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 内置识别器识别出的token类型
+const (
+	TypeURL     = "url"
+	TypeEmail   = "email"
+	TypeMention = "mention"
+	TypeHashtag = "hashtag"
+	TypeEmoji   = "emoji"
+	TypeIP      = "ip"
+	TypePhone   = "phone"
+)
+
+const (
+	maxMention = 30
+	maxHashtag = 64
+	// maxRegexpWindow 正则识别器每次最多匹配的字符数
+	maxRegexpWindow = 256
+)
+
+// Recognizer 在分词前识别特殊片段, 识别出的片段作为一个token, 不再参与分词.
+// text是规范化后的文本, 从text[start]开始识别, 返回片段的长度和类型, 长度为0表示没有识别出.
+// text[start]之前的字符可以用来判断边界, 例如邮箱中的@不能识别为@用户名
+type Recognizer interface {
+	Recognize(text []rune, start int) (int, string)
+}
+
+// RecognizerFunc 把函数作为Recognizer
+type RecognizerFunc func(text []rune, start int) (int, string)
+
+func (f RecognizerFunc) Recognize(text []rune, start int) (int, string) {
+	return f(text, start)
+}
+
+// NewRegexpRecognizer 使用正则识别, re需要以^开头, 匹配不能跨越空白字符
+func NewRegexpRecognizer(typ string, re *regexp.Regexp) Recognizer {
+	prefix, _ := re.LiteralPrefix()
+	return RecognizerFunc(func(text []rune, start int) (int, string) {
+		// 正则有固定前缀时先比较前缀, 大部分位置不需要执行正则
+		if !hasPrefix(text, start, prefix) {
+			return 0, ""
+		}
+		window := &runeWindow{text: text, pos: start, end: start + maxRegexpWindow}
+		if window.end > len(text) {
+			window.end = len(text)
+		}
+		loc := re.FindReaderIndex(window)
+		if loc == nil || loc[0] != 0 || loc[1] == 0 {
+			return 0, ""
+		}
+		// loc是utf8的字节偏移, 转换为字符数
+		end := start
+		for n := 0; n < loc[1]; end++ {
+			n += runeWidth(text[end])
+		}
+		return end - start, typ
+	})
+}
+
+// runeWindow 正则识别器的输入, 从text[pos]读到空白字符或end为止, 不需要把每个位置之后的文本转换为string
+type runeWindow struct {
+	text     []rune
+	pos, end int
+}
+
+func (w *runeWindow) ReadRune() (rune, int, error) {
+	if w.pos >= w.end || unicode.IsSpace(w.text[w.pos]) {
+		return 0, 0, io.EOF
+	}
+	r := w.text[w.pos]
+	w.pos++
+	if !utf8.ValidRune(r) {
+		r = utf8.RuneError
+	}
+	return r, runeWidth(r), nil
+}
+
+// runeWidth r转换为utf8的字节数, 同string([]rune)的转换, 非法字符转换为RuneError
+func runeWidth(r rune) int {
+	if n := utf8.RuneLen(r); n > 0 {
+		return n
+	}
+	return utf8.RuneLen(utf8.RuneError)
+}
+
+var (
+	URLRecognizer     Recognizer = RecognizerFunc(recognizeURL)
+	EmailRecognizer   Recognizer = RecognizerFunc(recognizeEmail)
+	MentionRecognizer Recognizer = RecognizerFunc(recognizeMention)
+	HashtagRecognizer Recognizer = RecognizerFunc(recognizeHashtag)
+	EmojiRecognizer   Recognizer = RecognizerFunc(recognizeEmoji)
+	IPRecognizer      Recognizer = RecognizerFunc(recognizeIP)
+	PhoneRecognizer   Recognizer = RecognizerFunc(recognizePhone)
+)
+
+// DefaultRecognizers 返回所有内置识别器, 可以在返回的列表上增删
+func DefaultRecognizers() []Recognizer {
+	return []Recognizer{
+		URLRecognizer,
+		EmailRecognizer,
+		IPRecognizer,
+		PhoneRecognizer,
+		MentionRecognizer,
+		HashtagRecognizer,
+		EmojiRecognizer,
+		QuantityRecognizer,
+	}
+}
+
+// ValueRecognizer 识别器同时给出片段的数值, 设置到SegToken.Value
+type ValueRecognizer interface {
+	Recognizer
+	// Value 识别出的片段word的数值
+	Value(word []rune) (float64, bool)
+}
+
+// recognizedPos 识别出的片段的词性, 其他类型为x
+var recognizedPos = map[string]string{
+	TypeNumber:   PosNumber,
+	TypeQuantity: PosQuantity,
+}
+
+// recognize 多个识别器都识别出时使用最长的, 长度相同时使用靠前的
+func recognize(recognizers []Recognizer, text []rune, start int) (Recognizer, int, string) {
+	var best Recognizer
+	bestLen, bestType := 0, ""
+	for _, recognizer := range recognizers {
+		if length, typ := recognizer.Recognize(text, start); length > bestLen {
+			best, bestLen, bestType = recognizer, length, typ
+		}
+	}
+	return best, bestLen, bestType
+}
+
+func newRecognizedToken(recognizer Recognizer, word []rune, offset int, typ string) *SegToken {
+	token := &SegToken{
+		Word:  string(word),
+		Start: offset,
+		End:   offset + len(word),
+		Pos:   PosUnknown,
+		Type:  typ,
+	}
+	if pos, ok := recognizedPos[typ]; ok {
+		token.Pos = pos
+	}
+	if vr, ok := recognizer.(ValueRecognizer); ok {
+		token.Value, _ = vr.Value(word)
+	}
+	return token
+}
+
+func isAsciiAlnum(r rune) bool {
+	return isEnglish(r) || isDigit(r)
+}
+
+// wordBoundary text[start]之前不是英文字母和数字
+func wordBoundary(text []rune, start int) bool {
+	return start == 0 || !isAsciiAlnum(text[start-1])
+}
+
+// hasPrefix text[start:]以prefix开头
+func hasPrefix(text []rune, start int, prefix string) bool {
+	pos := start
+	for _, r := range prefix {
+		if pos >= len(text) || text[pos] != r {
+			return false
+		}
+		pos++
+	}
+	return true
+}
+
+func hasPrefixFold(text []rune, start int, prefix string) bool {
+	if len(text)-start < len(prefix) {
+		return false
+	}
+	return strings.EqualFold(string(text[start:start+len(prefix)]), prefix)
+}
+
+func isURLChar(r rune) bool {
+	return isAsciiAlnum(r) || (r < utf8.RuneSelf && strings.ContainsRune("-._~:/?#[]@!$&'()*+,;=%", r))
+}
+
+// recognizeURL http://、https://、ftp://或www.开头, 直到空白或非ascii字符, 不包括末尾的标点
+func recognizeURL(text []rune, start int) (int, string) {
+	if !strings.ContainsRune("hfwHFW", text[start]) || !wordBoundary(text, start) {
+		return 0, ""
+	}
+	prefixLen := 0
+	for _, prefix := range []string{"http://", "https://", "ftp://", "www."} {
+		if hasPrefixFold(text, start, prefix) {
+			prefixLen = len(prefix)
+			break
+		}
+	}
+	if prefixLen == 0 {
+		return 0, ""
+	}
+	end := start + prefixLen
+	// 括号成对时末尾的)属于url, 如wiki的链接
+	opens, closes := 0, 0
+	for end < len(text) && isURLChar(text[end]) {
+		switch text[end] {
+		case '(':
+			opens++
+		case ')':
+			closes++
+		}
+		end++
+	}
+	for end > start+prefixLen && strings.ContainsRune(".,;:!?'\")]", text[end-1]) {
+		if text[end-1] == ')' {
+			if opens >= closes {
+				break
+			}
+			closes--
+		}
+		end--
+	}
+	if end == start+prefixLen {
+		return 0, ""
+	}
+	return end - start, TypeURL
+}
+
+func isEmailLocalChar(r rune) bool {
+	return isAsciiAlnum(r) || r == '.' || r == '_' || r == '%' || r == '+' || r == '-'
+}
+
+func isDomainChar(r rune) bool {
+	return isAsciiAlnum(r) || r == '-' || r == '.'
+}
+
+// recognizeEmail 用户名@域名, 域名至少两级, 顶级域名是至少两个字母
+func recognizeEmail(text []rune, start int) (int, string) {
+	if start > 0 && isEmailLocalChar(text[start-1]) {
+		return 0, ""
+	}
+	at := start
+	for at < len(text) && isEmailLocalChar(text[at]) {
+		at++
+	}
+	if at == start || at >= len(text) || text[at] != '@' {
+		return 0, ""
+	}
+	end := at + 1
+	for end < len(text) && isDomainChar(text[end]) {
+		end++
+	}
+	for end > at+1 && (text[end-1] == '.' || text[end-1] == '-') {
+		end--
+	}
+	domain := string(text[at+1 : end])
+	dot := strings.LastIndexByte(domain, '.')
+	if dot <= 0 || strings.Contains(domain, "..") {
+		return 0, ""
+	}
+	tld := domain[dot+1:]
+	if len(tld) < 2 {
+		return 0, ""
+	}
+	for _, r := range tld {
+		if !isEnglish(r) {
+			return 0, ""
+		}
+	}
+	return end - start, TypeEmail
+}
+
+func isMentionChar(r rune) bool {
+	return isAsciiAlnum(r) || r == '_' || r == '-' || isCjk(r)
+}
+
+// recognizeMention @用户名, 用户名由字母、数字、_、-和汉字组成(同微博), 最长30个字符
+func recognizeMention(text []rune, start int) (int, string) {
+	if text[start] != '@' || (start > 0 && isEmailLocalChar(text[start-1])) {
+		return 0, ""
+	}
+	end := start + 1
+	for end < len(text) && end-start <= maxMention && isMentionChar(text[end]) {
+		end++
+	}
+	if end == start+1 {
+		return 0, ""
+	}
+	return end - start, TypeMention
+}
+
+// recognizeHashtag 微博的#话题#, 或者twitter的#tag
+func recognizeHashtag(text []rune, start int) (int, string) {
+	if text[start] != '#' || !wordBoundary(text, start) {
+		return 0, ""
+	}
+	for end := start + 1; end < len(text) && end-start <= maxHashtag; end++ {
+		if text[end] == '#' {
+			if end > start+1 {
+				return end + 1 - start, TypeHashtag
+			}
+			break
+		}
+		if unicode.IsSpace(text[end]) {
+			break
+		}
+	}
+	end := start + 1
+	for end < len(text) && end-start <= maxHashtag && (isAsciiAlnum(text[end]) || text[end] == '_' || isCjk(text[end])) {
+		end++
+	}
+	if end == start+1 {
+		return 0, ""
+	}
+	return end - start, TypeHashtag
+}
+
+// recognizeIP ipv4或ipv6地址
+func recognizeIP(text []rune, start int) (int, string) {
+	if start > 0 && (isAsciiAlnum(text[start-1]) || text[start-1] == '.' || text[start-1] == ':') {
+		return 0, ""
+	}
+	end := start
+	sep := false
+	for end < len(text) && (unicode.Is(unicode.ASCII_Hex_Digit, text[end]) || text[end] == '.' || text[end] == ':') {
+		sep = sep || text[end] == '.' || text[end] == ':'
+		end++
+	}
+	// 没有分隔符时不是ip, 如英文单词deadbeef, 不需要解析
+	if !sep {
+		return 0, ""
+	}
+	if end < len(text) && isAsciiAlnum(text[end]) {
+		return 0, ""
+	}
+	// 去掉末尾的标点, ipv6末尾的::除外
+	for end > start+1 && (text[end-1] == '.' || (text[end-1] == ':' && text[end-2] != ':')) {
+		end--
+	}
+	if end-start < 3 {
+		return 0, ""
+	}
+	if _, err := netip.ParseAddr(string(text[start:end])); err != nil {
+		return 0, ""
+	}
+	return end - start, TypeIP
+}
+
+func scanDigits(text []rune, start int) int {
+	end := start
+	for end < len(text) && isDigit(text[end]) {
+		end++
+	}
+	return end - start
+}
+
+// recognizePhone 中国大陆的手机号和固定电话, 可以使用-分隔, 可以带+86前缀, 如 13712341234, 137-1234-1234, 010-12345678
+func recognizePhone(text []rune, start int) (int, string) {
+	if (!isDigit(text[start]) && text[start] != '+') || !wordBoundary(text, start) || (start > 0 && text[start-1] == '+') {
+		return 0, ""
+	}
+	pos := start
+	if hasPrefixFold(text, pos, "+86") {
+		pos += 3
+		if pos < len(text) && text[pos] == '-' {
+			pos++
+		}
+	}
+	numStart := pos
+	var groups []int
+	digits := 0
+	for {
+		n := scanDigits(text, pos)
+		if n == 0 {
+			return 0, ""
+		}
+		groups = append(groups, n)
+		digits += n
+		pos += n
+		if pos+1 < len(text) && text[pos] == '-' && isDigit(text[pos+1]) {
+			pos++
+			continue
+		}
+		break
+	}
+	if pos < len(text) && isAsciiAlnum(text[pos]) {
+		return 0, ""
+	}
+	ok := false
+	switch text[numStart] {
+	case '1':
+		// 手机号 11位, 第二位是3-9, 3-4-4分隔或不分隔
+		ok = digits == 11 && text[numStart+1] >= '3' && (len(groups) == 1 || (len(groups) == 3 && groups[0] == 3 && groups[1] == 4))
+	case '0':
+		// 固定电话 区号3-4位, 号码7-8位
+		ok = len(groups) == 2 && groups[0] >= 3 && groups[0] <= 4 && groups[1] >= 7 && groups[1] <= 8
+	}
+	if !ok {
+		return 0, ""
+	}
+	return pos - start, TypePhone
+}
+
+// emojiRanges 可以作为emoji的字符, Extended_Pictographic的近似
+var emojiRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x00a9, Hi: 0x00a9, Stride: 1},
+		{Lo: 0x00ae, Hi: 0x00ae, Stride: 1},
+		{Lo: 0x203c, Hi: 0x203c, Stride: 1},
+		{Lo: 0x2049, Hi: 0x2049, Stride: 1},
+		{Lo: 0x2122, Hi: 0x2122, Stride: 1},
+		{Lo: 0x2139, Hi: 0x2139, Stride: 1},
+		{Lo: 0x2194, Hi: 0x21aa, Stride: 1},
+		{Lo: 0x231a, Hi: 0x23ff, Stride: 1},
+		{Lo: 0x24c2, Hi: 0x24c2, Stride: 1},
+		{Lo: 0x25aa, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2600, Hi: 0x27bf, Stride: 1},
+		{Lo: 0x2934, Hi: 0x2935, Stride: 1},
+		{Lo: 0x2b05, Hi: 0x2b55, Stride: 1},
+		{Lo: 0x3030, Hi: 0x3030, Stride: 1},
+		{Lo: 0x303d, Hi: 0x303d, Stride: 1},
+		{Lo: 0x3297, Hi: 0x3297, Stride: 1},
+		{Lo: 0x3299, Hi: 0x3299, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f000, Hi: 0x1f1e5, Stride: 1},
+		{Lo: 0x1f200, Hi: 0x1f3fa, Stride: 1},
+		{Lo: 0x1f400, Hi: 0x1faff, Stride: 1},
+	},
+}
+
+const (
+	copyrightSign  = 0x00a9
+	registeredSign = 0x00ae
+	zwj            = 0x200d
+	variationSel16 = 0xfe0f
+	keycap         = 0x20e3
+	skinToneStart  = 0x1f3fb
+	skinToneEnd    = 0x1f3ff
+	regionalStart  = 0x1f1e6
+	regionalEnd    = 0x1f1ff
+	emojiTagStart  = 0xe0020
+	emojiTagEnd    = 0xe007f
+)
+
+func isRegional(r rune) bool {
+	return r >= regionalStart && r <= regionalEnd
+}
+
+// emojiElement 识别一个emoji, 包括修饰符, 返回结束位置, 不是emoji时返回start
+func emojiElement(text []rune, start int) int {
+	r := text[start]
+	end := start + 1
+	switch {
+	case isRegional(r):
+		// 国旗, 两个区域指示符
+		if end < len(text) && isRegional(text[end]) {
+			return end + 1
+		}
+		return end
+	case isDigit(r) || r == '#' || r == '*':
+		// 键帽 1️⃣
+		if end < len(text) && text[end] == variationSel16 {
+			end++
+		}
+		if end < len(text) && text[end] == keycap {
+			return end + 1
+		}
+		return start
+	case !unicode.Is(emojiRanges, r):
+		return start
+	}
+	if end < len(text) && text[end] == variationSel16 {
+		end++
+	} else if r == copyrightSign || r == registeredSign {
+		// ©和®默认是文本, 只有带VS16时是emoji
+		return start
+	}
+	if end < len(text) && text[end] >= skinToneStart && text[end] <= skinToneEnd {
+		end++
+	}
+	// 旗帜的tag序列, 如英格兰
+	for end < len(text) && text[end] >= emojiTagStart && text[end] <= emojiTagEnd {
+		end++
+	}
+	return end
+}
+
+// recognizeEmoji emoji序列, 包括肤色、ZWJ连接的组合emoji、国旗和键帽
+func recognizeEmoji(text []rune, start int) (int, string) {
+	end := emojiElement(text, start)
+	if end == start {
+		return 0, ""
+	}
+	for end+1 < len(text) && text[end] == zwj {
+		next := emojiElement(text, end+1)
+		if next == end+1 {
+			break
+		}
+		end = next
+	}
+	return end - start, TypeEmoji
+}
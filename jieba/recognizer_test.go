@@ -0,0 +1,120 @@
+package jieba
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRecognizers(t *testing.T) {
+	handler := loadTestHandler(t)
+	opts := &SegOptions{Mode: ModeSearch, Recognizers: DefaultRecognizers()}
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{"RT @laoshipukong : 27日", "mention:@laoshipukong quantity:27日"},
+		{"访问https://example.com/a?b=1&c=(2)。", "url:https://example.com/a?b=1&c=(2)"},
+		{"见www.example.com/path.", "url:www.example.com/path"},
+		{"(见https://en.wikipedia.org/wiki/Go_(language))", "url:https://en.wikipedia.org/wiki/Go_(language)"},
+		{"发邮件到foo.bar+x@mail.example.com,谢谢", "email:foo.bar+x@mail.example.com"},
+		{"#我来到北京#很开心 #golang", "hashtag:#我来到北京# hashtag:#golang"},
+		{"点赞👍🏽和👨‍👩‍👧‍👦还有🇨🇳1️⃣", "emoji:👍🏽 emoji:👨‍👩‍👧‍👦 emoji:🇨🇳 emoji:1️⃣"},
+		{"©公司®和©️", "emoji:©️"},
+		{"服务器192.168.1.10和fe80::1:ab正常", "ip:192.168.1.10 ip:fe80::1:ab"},
+		{"电话137-1234-1234或010-12345678或+8613712341234", "phone:137-1234-1234 phone:010-12345678 phone:+8613712341234"},
+		{"版本1.2不是ip, 12345678901不是手机号, c#不是话题", "number:1.2 number:12345678901"},
+	}
+	for _, c := range cases {
+		tokens := handler.SegParagraphWithOptions(c.input, opts)
+		fmt.Println(tokens)
+		var typed []string
+		for _, token := range tokens {
+			if token.Type != "" {
+				typed = append(typed, token.Type+":"+token.Text)
+			}
+		}
+		if strings.Join(typed, " ") != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.input, c.expect, typed)
+		}
+
+		stream := handler.NewTokenStream(strings.NewReader(c.input), opts)
+		for i := 0; stream.Next(); i++ {
+			if *stream.Token() != *tokens[i] {
+				t.Errorf("stream token %d differs: %+v, %+v", i, stream.Token(), tokens[i])
+			}
+		}
+	}
+
+	tokens := handler.SegParagraphWithOptions("RT @laoshipukong : 27日", opts)
+	if fmt.Sprint(tokenWords(tokens)) != "[rt   @laoshipukong   :   27日]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+	// 不使用识别器时不变
+	tokens = handler.SegParagraph("RT @laoshipukong : 27日", ModeSearch)
+	if fmt.Sprint(tokenWords(tokens)) != "[rt   @ laoshipukong   :   27 日]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
+
+func TestRegexpRecognizer(t *testing.T) {
+	handler := loadTestHandler(t)
+	order := NewRegexpRecognizer("order", regexp.MustCompile(`^no\.\d{6}`))
+	opts := &SegOptions{Recognizers: append(DefaultRecognizers(), order)}
+	tokens := handler.SegParagraphWithOptions("订单No.123456已发货", opts)
+	if len(tokens) < 2 || tokens[1].Type != "order" || tokens[1].Word != "no.123456" || tokens[1].Text != "No.123456" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+
+	// 没有固定前缀的正则, 匹配到空白字符为止, 长度是字符数
+	code := NewRegexpRecognizer("code", regexp.MustCompile(`^编号\d+(-\d+)?`))
+	opts = &SegOptions{Recognizers: []Recognizer{code}}
+	tokens = handler.SegParagraphWithOptions("编号12 -3和编号45-6", opts)
+	fmt.Println(tokens)
+	var codes []string
+	for _, token := range tokens {
+		if token.Type == "code" {
+			codes = append(codes, token.Word)
+		}
+	}
+	if fmt.Sprint(codes) != "[编号12 编号45-6]" {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
+
+func TestQuantityRecognizer(t *testing.T) {
+	handler := loadTestHandler(t)
+	opts := &SegOptions{Recognizers: []Recognizer{QuantityRecognizer}}
+
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{"两块五一套，三块八一斤", "quantity:两块五=2.5 quantity:一套=1 quantity:三块八=3.8 quantity:一斤=1"},
+		{"二〇二四年", "quantity:二〇二四年=2024"},
+		{"18.90元", "quantity:18.90元=18.9"},
+		{"60周年", "quantity:60周年=60"},
+		{"增长了百分之五十，达到35%", "quantity:百分之五十=50 quantity:35%=35"},
+		{"三块五毛八，三块一斤", "quantity:三块五毛八=3.58 quantity:三块=3 quantity:一斤=1"},
+		{"1,234.5万元和1.5万", "quantity:1,234.5万元=1.2345e+07 number:1.5万=15000"},
+		{"一万零五百和两千五和三百二十五", "number:一万零五百=10500 number:两千五=2500 number:三百二十五=325"},
+		{"第三十届跑了五千米", "quantity:第三十届=30 quantity:五千米=5"},
+		{"统一，一本正经，一部分，万一，千万，三五成群，十分", ""},
+		{"iphone5和5g", ""},
+	}
+	for _, c := range cases {
+		tokens := handler.SegParagraphWithOptions(c.input, opts)
+		fmt.Println(tokens)
+		var typed []string
+		for _, token := range tokens {
+			if token.Type != "" {
+				typed = append(typed, fmt.Sprintf("%s:%s=%v", token.Type, token.Word, token.Value))
+			}
+		}
+		if strings.Join(typed, " ") != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.input, c.expect, typed)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package jieba
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxStreamSentence 流式分词时单个句子的最大长度, 超过时强制切分, 保证内存有界
+const maxStreamSentence = 8192
+
+// TokenStream 从io.Reader流式分词, 在SegParagraph相同的边界(不能参与分词的字符)处切分句子,
+// token的偏移是相对整个流的绝对偏移. 用法同bufio.Scanner:
+//
+//	stream := handler.NewTokenStream(reader, nil)
+//	for stream.Next() {
+//		token := stream.Token()
+//	}
+//	if err := stream.Err(); err != nil {
+//	}
+type TokenStream struct {
+	handler  *SegmentHandler
+	opts     *SegOptions
+	reader   *bufio.Reader
+	sentence []rune
+	// offset sentence在流中的起始位置
+	offset int
+	// offsets sentence中每个rune的字节偏移和utf16偏移, bytePos 下一个rune的字节偏移
+	offsets *offsetTable
+	bytePos int
+	// raw sentence的原始字节
+	raw     []byte
+	runeBuf [utf8.UTFMax]byte
+	pending []*SegToken
+	// position 下一个token的Position
+	position int
+	next     int
+	token    *SegToken
+	err      error
+	eof      bool
+}
+
+func (h *SegmentHandler) NewTokenStream(r io.Reader, opts *SegOptions) *TokenStream {
+	if opts == nil {
+		opts = &SegOptions{}
+	}
+	return &TokenStream{
+		handler: h,
+		opts:    opts,
+		reader:  bufio.NewReader(r),
+		offsets: newOffsetTable(0),
+	}
+}
+
+// Next 读取下一个token, 没有更多token或者出错时返回false
+func (ts *TokenStream) Next() bool {
+	for ts.next >= len(ts.pending) {
+		if ts.eof {
+			ts.token = nil
+			return false
+		}
+		ts.fill()
+	}
+	ts.token = ts.pending[ts.next]
+	ts.pending[ts.next] = nil
+	ts.next++
+	return true
+}
+
+func (ts *TokenStream) Token() *SegToken {
+	return ts.token
+}
+
+func (ts *TokenStream) Err() error {
+	return ts.err
+}
+
+// fill 读取到一个边界, 把之前的内容分词后放入pending, 句子超过maxStreamSentence时按cutPoint切分
+func (ts *TokenStream) fill() {
+	ts.pending = ts.pending[:0]
+	ts.next = 0
+	for {
+		r, rawRune, err := ts.readRune()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				ts.err = err
+			}
+			ts.eof = true
+			ts.flush(len(ts.sentence))
+			return
+		}
+		nr := regularize(r, ts.opts)
+		ts.offsets.add(ts.bytePos, r)
+		ts.bytePos += len(rawRune)
+		ts.raw = append(ts.raw, rawRune...)
+		ts.sentence = append(ts.sentence, nr)
+
+		if ts.boundary(nr) {
+			ts.flush(len(ts.sentence))
+			return
+		}
+		if len(ts.sentence) >= maxStreamSentence {
+			ts.flush(ts.cutPoint())
+			return
+		}
+	}
+}
+
+// boundary 没有识别器时边界是不能参与分词的字符, 同SegParagraph的句子边界;
+// 有识别器时只有空白字符, 识别的片段可能包含标点, 如url和email
+func (ts *TokenStream) boundary(nr rune) bool {
+	if len(ts.opts.Recognizers) == 0 {
+		return !couldTrieSegSupport(nr)
+	}
+	return unicode.IsSpace(nr)
+}
+
+// cutPoint 句子超过maxStreamSentence时的切分位置. 有识别器时同segRunes从头扫描, 在最后一个没有被识别的
+// 中文标点等非ascii分隔符之后切分, 末尾留出maxHashtag+1个字符, 保证切分前的识别结果不受后面内容的影响,
+// 内置识别器中只有#话题#可以包含非ascii标点. 没有这样的位置时在末尾强制切分
+func (ts *TokenStream) cutPoint() int {
+	cut := len(ts.sentence)
+	if len(ts.opts.Recognizers) == 0 {
+		return cut
+	}
+	limit := len(ts.sentence) - maxHashtag - 1
+	for i := 0; i < limit; {
+		if _, length, _ := recognize(ts.opts.Recognizers, ts.sentence, i); length > 0 {
+			i += length
+			continue
+		}
+		r := ts.sentence[i]
+		i++
+		if r >= utf8.RuneSelf && !couldTrieSegSupport(r) {
+			cut = i
+		}
+	}
+	return cut
+}
+
+// flush 把sentence的前n个字符分词后放入pending, 剩下的字符留到下一次
+func (ts *TokenStream) flush(n int) {
+	if n == 0 {
+		return
+	}
+	ot := ts.offsets
+	byteEnd, nextUtf16 := ts.bytePos, ot.nextUtf16
+	var restBytes, restUtf16 []int
+	var restRaw []byte
+	var rest []rune
+	if n < len(ts.sentence) {
+		byteEnd, ot.nextUtf16 = ot.bytes[n], ot.utf16[n]
+		restBytes, restUtf16 = slices.Clone(ot.bytes[n:]), slices.Clone(ot.utf16[n:])
+		restRaw = slices.Clone(ts.raw[byteEnd-ot.bytes[0]:])
+		rest = slices.Clone(ts.sentence[n:])
+		ot.bytes, ot.utf16 = ot.bytes[:n], ot.utf16[:n]
+	}
+
+	from := len(ts.pending)
+	ts.pending = ts.handler.segRunes(ts.pending, ts.sentence[:n], ts.offset, ts.opts)
+	ot.finish(byteEnd)
+	ot.fill(ts.pending[from:], ts.offset, string(ts.raw[:len(ts.raw)-len(restRaw)]))
+	ts.position = setPositions(ts.pending[from:], ts.position)
+
+	ot.reset(nextUtf16)
+	ot.bytes = append(ot.bytes, restBytes...)
+	ot.utf16 = append(ot.utf16, restUtf16...)
+	ts.raw = append(ts.raw[:0], restRaw...)
+	ts.offset += n
+	ts.sentence = append(ts.sentence[:0], rest...)
+}
+
+// readRune 同bufio.Reader.ReadRune, 同时返回rune的原始字节, 非法的utf8字节也能原样保留
+func (ts *TokenStream) readRune() (rune, []byte, error) {
+	buf, err := ts.reader.Peek(utf8.UTFMax)
+	if len(buf) == 0 {
+		return 0, nil, err
+	}
+	r, size := utf8.DecodeRune(buf)
+	raw := ts.runeBuf[:copy(ts.runeBuf[:], buf[:size])]
+	if _, err = ts.reader.Discard(size); err != nil {
+		return 0, nil, err
+	}
+	return r, raw, nil
+}
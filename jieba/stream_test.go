@@ -0,0 +1,75 @@
+package jieba
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenStream(t *testing.T) {
+	handler := loadTestHandler(t)
+	content := strings.Repeat("我来到北京清华大学，中华人民共和国站起来了。\n", 100)
+
+	expect := handler.SegParagraph(content, ModeIndex)
+	stream := handler.NewTokenStream(strings.NewReader(content), &SegOptions{Mode: ModeIndex})
+	var got []*SegToken
+	for stream.Next() {
+		got = append(got, stream.Token())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Errorf("stream tokens differ from SegParagraph")
+	}
+	if last := got[len(got)-1]; last.End != len([]rune(content)) {
+		t.Errorf("bad absolute offset %s", last)
+	}
+}
+
+func TestTokenStreamLongSentence(t *testing.T) {
+	handler := loadTestHandler(t)
+	content := strings.Repeat("北京", maxStreamSentence) + "，大学"
+
+	stream := handler.NewTokenStream(strings.NewReader(content), nil)
+	offset := 0
+	for stream.Next() {
+		token := stream.Token()
+		if token.Start != offset {
+			t.Fatalf("expect start %d, got %s", offset, token)
+		}
+		offset = token.End
+	}
+	if offset != len([]rune(content)) {
+		t.Errorf("expect end %d, got %d", len([]rune(content)), offset)
+	}
+}
+
+func TestTokenStreamRecognizersUnspaced(t *testing.T) {
+	handler := loadTestHandler(t)
+	// 没有空白, 超过maxStreamSentence时在中文标点处切分, 结果同SegParagraph
+	content := strings.Repeat("我来到北京清华大学，联系abc@example.com或#话题，一#。𠀀ＡＢ", 400)
+	opts := &SegOptions{Recognizers: DefaultRecognizers()}
+
+	expect := handler.SegParagraphWithOptions(content, opts)
+	stream := handler.NewTokenStream(strings.NewReader(content), opts)
+	var got []*SegToken
+	for stream.Next() {
+		got = append(got, stream.Token())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len([]rune(content)) <= maxStreamSentence {
+		t.Fatalf("content too short")
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("expect %d tokens, got %d", len(expect), len(got))
+	}
+	// 切分处之后的字节偏移、utf16偏移和原始文本也要一致
+	for i, token := range got {
+		if *token != *expect[i] {
+			t.Fatalf("expect %+v, got %+v", expect[i], token)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package jieba
 import (
 	"regexp"
 	"slices"
+	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -10,10 +12,39 @@ const (
 	cjkNormalEnd   = 0x9FFF
 )
 
+// CharRange 字符区间[Lo, Hi]
+type CharRange struct {
+	Lo rune
+	Hi rune
+}
+
+// defaultCjkRanges 所有汉字区间, 按Unicode 15.1
+var defaultCjkRanges = []CharRange{
+	{0x3007, 0x3007},   // 〇
+	{0x3400, 0x4DBF},   // 扩展A
+	{0x4E00, 0x9FFF},   // 基本区
+	{0xF900, 0xFAFF},   // 兼容汉字
+	{0x20000, 0x2A6DF}, // 扩展B
+	{0x2A700, 0x2B73F}, // 扩展C
+	{0x2B740, 0x2B81F}, // 扩展D
+	{0x2B820, 0x2CEAF}, // 扩展E
+	{0x2CEB0, 0x2EBEF}, // 扩展F
+	{0x2EBF0, 0x2EE5F}, // 扩展I
+	{0x2F800, 0x2FA1F}, // 兼容汉字补充
+	{0x30000, 0x3134F}, // 扩展G
+	{0x31350, 0x323AF}, // 扩展H
+}
+
+// cjkRanges 有序且不重叠, 修改时整体替换, 读取不需要加锁
+var cjkRanges atomic.Pointer[[]CharRange]
+var cjkRangesLock sync.Mutex
+
 var connectors = []rune{'+', '#', '&', '.', '_', '-'}
 var reSkip *regexp.Regexp
 
 func init() {
+	ranges := mergeRanges(defaultCjkRanges)
+	cjkRanges.Store(&ranges)
 	slices.Sort(connectors)
 	var err error
 	reSkip, err = regexp.Compile("(\\d+\\.\\d+|[a-zA-Z0-9]+)")
@@ -22,8 +53,56 @@ func init() {
 	}
 }
 
-func isCjkNormal(r rune) bool {
-	return r >= cjkNormalStart && r <= cjkNormalEnd
+// CjkRanges 返回当前作为汉字处理的字符区间
+func CjkRanges() []CharRange {
+	return slices.Clone(*cjkRanges.Load())
+}
+
+// AddCjkRanges 把ranges中的字符作为汉字处理, 参与词典匹配和hmm新词发现, 例如私有区的造字.
+// 影响所有SegmentHandler, 可以和分词并发调用
+func AddCjkRanges(ranges ...CharRange) {
+	cjkRangesLock.Lock()
+	defer cjkRangesLock.Unlock()
+	merged := mergeRanges(append(CjkRanges(), ranges...))
+	cjkRanges.Store(&merged)
+}
+
+func mergeRanges(ranges []CharRange) []CharRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b CharRange) int {
+		return int(a.Lo - b.Lo)
+	})
+	var merged []CharRange
+	for _, cr := range sorted {
+		if cr.Lo > cr.Hi {
+			continue
+		}
+		if n := len(merged); n > 0 && cr.Lo <= merged[n-1].Hi+1 {
+			if cr.Hi > merged[n-1].Hi {
+				merged[n-1].Hi = cr.Hi
+			}
+			continue
+		}
+		merged = append(merged, cr)
+	}
+	return merged
+}
+
+func isCjk(r rune) bool {
+	if r >= cjkNormalStart && r <= cjkNormalEnd {
+		return true
+	}
+	ranges := *cjkRanges.Load()
+	i, _ := slices.BinarySearchFunc(ranges, r, func(cr CharRange, r rune) int {
+		if cr.Hi < r {
+			return -1
+		}
+		if cr.Lo > r {
+			return 1
+		}
+		return 0
+	})
+	return i < len(ranges) && ranges[i].Lo <= r && r <= ranges[i].Hi
 }
 
 func isEnglish(r rune) bool {
@@ -39,22 +118,23 @@ func isConnector(r rune) bool {
 	return found
 }
 
-func regularize(input rune) rune {
+// regularize 按opts规范化字符, 先全角转半角再大写转小写, 全角的大写字母转成半角的小写字母
+func regularize(input rune, opts *SegOptions) rune {
 	// 全角空格
-	if input == 12288 {
+	if input == 12288 && !opts.KeepFullWidthSpace {
 		return 32
 	}
-	// 繁体->简体
-	if input > 65280 && input < 65375 {
-		return input - 65248
+	// 全角->半角
+	if input > 65280 && input < 65375 && !opts.KeepFullWidth {
+		input -= 65248
 	}
 	// 大写转小写
-	if input >= 'A' && input <= 'Z' {
+	if input >= 'A' && input <= 'Z' && !opts.KeepCase {
 		return input + 32
 	}
 	return input
 }
 
 func couldTrieSegSupport(r rune) bool {
-	return isCjkNormal(r) || isEnglish(r) || isDigit(r) || isConnector(r)
+	return isCjk(r) || isEnglish(r) || isDigit(r) || isConnector(r)
 }
@@ -1,22 +1,39 @@
 package jieba
 
 import (
-	"bufio"
 	"cmp"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path"
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+const (
+	IdfDictName      = "idf_dict.txt"
+	IdfStopWordsName = "idf_stop_words.txt"
 )
 
 func NewTfidf(rootPath string, segHandler *SegmentHandler) (Tfidf, error) {
-	idfMap, err := loadTfidfDict(rootPath)
+	return NewTfidfFS(os.DirFS(rootPath), segHandler)
+}
+
+// NewTfidfFS 从fsys加载idf词典和停用词, fsys的根目录对应dict目录
+func NewTfidfFS(fsys fs.FS, segHandler *SegmentHandler) (Tfidf, error) {
+	return NewTfidfFSWithOptions(fsys, segHandler, nil)
+}
+
+// NewTfidfFSWithOptions 同NewTfidfFS, 通过opts选择严格或宽松模式, 见dictload.Options
+func NewTfidfFSWithOptions(fsys fs.FS, segHandler *SegmentHandler, opts *dictload.Options) (Tfidf, error) {
+	idfMap, err := loadTfidfDict(fsys, opts)
 	if err != nil {
 		return nil, err
 	}
-	stopWords, err := loadStopWord(rootPath)
+	stopWords, err := loadStopWord(fsys, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -28,35 +45,39 @@ func NewTfidf(rootPath string, segHandler *SegmentHandler) (Tfidf, error) {
 	}, nil
 }
 
-func loadTfidfDict(rootPath string) (map[string]float64, error) {
-	fileFullName := path.Join(rootPath, "idf_dict.txt")
-	f, err := os.Open(fileFullName)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	stopMap := map[string]float64{}
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		line := scan.Text()
-		line = strings.TrimSpace(line)
+// loadTfidfDict 每行: 词 idf
+func loadTfidfDict(fsys fs.FS, opts *dictload.Options) (map[string]float64, error) {
+	idfMap := map[string]float64{}
+	_, err := dictload.Load(fsys, IdfDictName, opts, func(line string) error {
+		items := strings.Fields(line)
+		if len(items) == 0 {
+			return dictload.ErrSkip
+		}
+		if len(items) != 2 {
+			return errors.New("bad idf items:" + line)
+		}
+		idf, err := strconv.ParseFloat(items[1], 64)
 		if err != nil {
-			return nil, err
+			return errors.New("bad idf:" + line)
 		}
-
-		items := strings.Fields(line)
-
-		freq, _ := strconv.ParseFloat(items[1], 64)
-
-		stopMap[items[1]] = freq
+		if _, ok := idfMap[items[0]]; ok {
+			return dictload.ErrDuplicate
+		}
+		idfMap[items[0]] = idf
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return stopMap, scan.Err()
+	return idfMap, nil
 }
 
+// calMedium idf的中位数, 用于idf词典中没有的词, 词典为空时返回0
 func calMedium(idMap map[string]float64) float64 {
 	l := len(idMap)
+	if l == 0 {
+		return 0
+	}
 	list := make([]float64, 0, l)
 	for _, freq := range idMap {
 		list = append(list, freq)
@@ -65,28 +86,23 @@ func calMedium(idMap map[string]float64) float64 {
 	return list[l/2]
 }
 
-func loadStopWord(rootPath string) (map[string]struct{}, error) {
-	fileFullName := path.Join(rootPath, "idf_stop_words.txt")
-
-	f, err := os.Open(fileFullName)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
+func loadStopWord(fsys fs.FS, opts *dictload.Options) (map[string]struct{}, error) {
 	stopMap := map[string]struct{}{}
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		line := scan.Text()
+	_, err := dictload.Load(fsys, IdfStopWordsName, opts, func(line string) error {
 		line = strings.TrimSpace(line)
-		if err != nil {
-			return nil, err
+		if line == "" {
+			return dictload.ErrSkip
+		}
+		if _, ok := stopMap[line]; ok {
+			return dictload.ErrDuplicate
 		}
-
 		stopMap[line] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return stopMap, scan.Err()
+	return stopMap, nil
 }
 
 type Keyword struct {
@@ -144,12 +160,13 @@ func (tf *tfIdfImpl) TopNByString(input string, n int) []*Keyword {
 }
 
 func (tf *tfIdfImpl) getTf(input []rune) map[string]float64 {
-	tokens := tf.segHandler.segSentence(input)
+	tokens := tf.segHandler.segSentence(input, &SegOptions{})
 
 	freqMap := map[string]int{}
 
 	wordCount := 0
-	for _, token := range tokens {
+	for _, wt := range tokens {
+		token := wt.word
 		if len(token) <= 1 {
 			continue
 		}
@@ -0,0 +1,611 @@
+package jieba
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const TypeTime = "time"
+
+// TimeRange 时间区间[Start, End), 相对时长如"3天后"解析为一个时刻, Start等于End
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// timePeriod 一天中的时段, 小时区间[start, end]
+type timePeriod struct {
+	start, end int
+}
+
+var timePeriods = map[string]timePeriod{
+	"凌晨": {0, 6},
+	"清晨": {5, 8},
+	"早上": {6, 9},
+	"早晨": {6, 9},
+	"上午": {9, 12},
+	"中午": {11, 14},
+	"下午": {13, 18},
+	"傍晚": {17, 19},
+	"晚上": {18, 24},
+	"夜里": {21, 24},
+	"夜间": {21, 24},
+}
+
+var relativeYears = map[string]int{
+	"今年": 0, "本年": 0, "明年": 1, "来年": 1, "后年": 2, "去年": -1, "前年": -2,
+}
+
+var relativeMonths = map[string]int{
+	"本月": 0, "这个月": 0, "下月": 1, "下个月": 1, "上月": -1, "上个月": -1,
+}
+
+var relativeDays = map[string]int{
+	"今天": 0, "今日": 0, "明天": 1, "明日": 1, "后天": 2, "大后天": 3,
+	"昨天": -1, "昨日": -1, "前天": -2, "大前天": -3,
+}
+
+// dayPeriods 日期和时段合在一起的词, 如 今晚=今天晚上
+var dayPeriods = map[string]struct {
+	day    int
+	period string
+}{
+	"今早": {0, "早上"}, "今晨": {0, "早晨"}, "今晚": {0, "晚上"},
+	"明早": {1, "早上"}, "明晚": {1, "晚上"},
+	"昨晚": {-1, "晚上"}, "昨夜": {-1, "夜里"},
+}
+
+var relativeWeeks = map[string]int{
+	"本周": 0, "这周": 0, "本星期": 0, "这星期": 0, "这个星期": 0, "这礼拜": 0, "这个礼拜": 0,
+	"下周": 1, "下星期": 1, "下个星期": 1, "下礼拜": 1, "下个礼拜": 1,
+	"上周": -1, "上星期": -1, "上个星期": -1, "上礼拜": -1, "上个礼拜": -1,
+}
+
+var weekPrefixes = []string{"星期", "礼拜", "周"}
+
+var weekdays = map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '日': 7, '天': 7}
+
+// durationUnits 相对时长的单位, 如 3天后 两个月前
+var durationUnits = map[string]timeGrain{
+	"年": grainYear, "个月": grainMonth, "周": grainWeek, "星期": grainWeek, "个星期": grainWeek,
+	"天": grainDay, "小时": grainHour, "个小时": grainHour, "分钟": grainMinute, "秒": grainSecond, "秒钟": grainSecond,
+}
+
+var durationDirections = map[string]int{
+	"前": -1, "以前": -1, "之前": -1, "后": 1, "以后": 1, "之后": 1,
+}
+
+type timeGrain int
+
+const (
+	grainNone timeGrain = iota
+	grainYear
+	grainMonth
+	grainWeek
+	grainDay
+	grainHour
+	grainMinute
+	grainSecond
+)
+
+// timeExpr 解析出的时间表达式, 没有出现的数值字段为-1
+type timeExpr struct {
+	year, month, day              int
+	yearOffset, monthOffset       int
+	dayOffset, weekOffset         int
+	hasYearOffset, hasMonthOffset bool
+	hasDayOffset, hasWeekOffset   bool
+	weekday                       int
+	period                        string
+	hour, minute, second          int
+
+	// 相对时长
+	durationGrain timeGrain
+	duration      int
+}
+
+func newTimeExpr() *timeExpr {
+	return &timeExpr{year: -1, month: -1, day: -1, hour: -1, minute: -1, second: -1}
+}
+
+// hasDate 已经解析出日期部分
+func (e *timeExpr) hasDate() bool {
+	return e.year >= 0 || e.month >= 0 || e.day >= 0 || e.hasYearOffset || e.hasMonthOffset ||
+		e.hasDayOffset || e.hasWeekOffset || e.weekday > 0
+}
+
+// MergeTime 把tokens中连续的表示日期和时间的token合并为一个token, Type为TypeTime, 词性为t,
+// 如 [2024 年 3 月 5 日] [下周 三 上午 十点] [前天 晚上], 可以使用ResolveTime解析合并后的Word.
+// tokens需要是SegParagraph的ModeSearch结果这样不重叠的token, 时间表达式的边界必须和token的边界一致, 合并后重新计算Position
+func MergeTime(tokens []*SegToken) []*SegToken {
+	merged := make([]*SegToken, 0, len(tokens))
+	for i := 0; i < len(tokens); {
+		j := i + 1
+		for j < len(tokens) && tokens[j].Start == tokens[j-1].End {
+			j++
+		}
+		merged = mergeTimeRun(merged, tokens[i:j])
+		i = j
+	}
+	if len(merged) > 0 {
+		setPositions(merged, tokens[0].Position)
+	}
+	return merged
+}
+
+// mergeTimeRun 合并一段连续的token, 从每个token开始识别最长的、结束于token边界的时间表达式
+func mergeTimeRun(merged []*SegToken, run []*SegToken) []*SegToken {
+	var text []rune
+	bounds := make([]int, 0, len(run)+1)
+	for _, token := range run {
+		bounds = append(bounds, len(text))
+		text = append(text, []rune(token.Word)...)
+	}
+	bounds = append(bounds, len(text))
+
+	for i := 0; i < len(run); {
+		count := 0
+		for end := len(run); end > i; {
+			length, _ := scanTime(text[:bounds[end]], bounds[i])
+			if length == 0 {
+				break
+			}
+			last := i
+			for last < end && bounds[last+1] <= bounds[i]+length {
+				last++
+			}
+			if bounds[last] == bounds[i]+length {
+				count = last - i
+				break
+			}
+			// 表达式结束在token中间, 缩短文本后重新识别
+			end = last
+		}
+		if count == 0 {
+			merged = append(merged, run[i])
+			i++
+			continue
+		}
+		merged = append(merged, newTimeToken(run[i:i+count]))
+		i += count
+	}
+	return merged
+}
+
+func newTimeToken(tokens []*SegToken) *SegToken {
+	first, last := tokens[0], tokens[len(tokens)-1]
+	var word, text strings.Builder
+	for _, token := range tokens {
+		word.WriteString(token.Word)
+		text.WriteString(token.Text)
+	}
+	return &SegToken{
+		Word:       word.String(),
+		Text:       text.String(),
+		Start:      first.Start,
+		End:        last.End,
+		Pos:        PosTime,
+		ByteStart:  first.ByteStart,
+		ByteEnd:    last.ByteEnd,
+		Utf16Start: first.Utf16Start,
+		Utf16End:   last.Utf16End,
+		Type:       TypeTime,
+	}
+}
+
+// ResolveTime 以ref为参考时间把时间表达式解析为时间区间, 如 ref为2024-03-10时"下周三上午十点"为[2024-03-13 10:00, 11:00).
+// 结果使用ref的时区, 没有给出的部分使用ref的值, 如"27日"为ref当月的27日, 不推测过去或将来.
+// expr不是完整的时间表达式或者日期不存在时返回false
+func ResolveTime(expr string, ref time.Time) (TimeRange, bool) {
+	text := []rune(expr)
+	for i, r := range text {
+		text[i] = regularize(r, &SegOptions{})
+	}
+	if len(text) == 0 {
+		return TimeRange{}, false
+	}
+	length, e := scanTime(text, 0)
+	if length != len(text) {
+		return TimeRange{}, false
+	}
+	return e.resolve(ref)
+}
+
+func (e *timeExpr) resolve(ref time.Time) (TimeRange, bool) {
+	if e.durationGrain != grainNone {
+		t := ref
+		switch e.durationGrain {
+		case grainYear:
+			t = t.AddDate(e.duration, 0, 0)
+		case grainMonth:
+			t = t.AddDate(0, e.duration, 0)
+		case grainWeek:
+			t = t.AddDate(0, 0, 7*e.duration)
+		case grainDay:
+			t = t.AddDate(0, 0, e.duration)
+		case grainHour:
+			t = t.Add(time.Duration(e.duration) * time.Hour)
+		case grainMinute:
+			t = t.Add(time.Duration(e.duration) * time.Minute)
+		case grainSecond:
+			t = t.Add(time.Duration(e.duration) * time.Second)
+		}
+		return TimeRange{Start: t, End: t}, true
+	}
+
+	loc := ref.Location()
+	year, month, day := ref.Date()
+	grain := grainNone
+	if e.year >= 0 {
+		year, grain = e.year, grainYear
+	} else if e.hasYearOffset {
+		year, grain = year+e.yearOffset, grainYear
+	}
+	if e.month >= 0 {
+		month, grain = time.Month(e.month), grainMonth
+	} else if e.hasMonthOffset {
+		t := time.Date(year, month+time.Month(e.monthOffset), 1, 0, 0, 0, 0, loc)
+		year, month, grain = t.Year(), t.Month(), grainMonth
+	} else if grain == grainYear {
+		month = 1
+	}
+	switch {
+	case e.day >= 0:
+		if e.day > daysIn(year, month) {
+			return TimeRange{}, false
+		}
+		day, grain = e.day, grainDay
+	case e.hasDayOffset:
+		year, month, day = time.Date(year, month, day+e.dayOffset, 0, 0, 0, 0, loc).Date()
+		grain = grainDay
+	case e.hasWeekOffset || e.weekday > 0:
+		// 一周从周一开始
+		weekday := int(ref.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		offset := 7*e.weekOffset - weekday + 1
+		grain = grainWeek
+		if e.weekday > 0 {
+			offset += e.weekday - 1
+			grain = grainDay
+		}
+		year, month, day = time.Date(year, month, day+offset, 0, 0, 0, 0, loc).Date()
+	case grain != grainNone:
+		day = 1
+	}
+
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	var end time.Time
+	switch grain {
+	case grainYear:
+		end = start.AddDate(1, 0, 0)
+	case grainMonth:
+		end = start.AddDate(0, 1, 0)
+	case grainWeek:
+		end = start.AddDate(0, 0, 7)
+	default:
+		end = start.AddDate(0, 0, 1)
+	}
+
+	period, hasPeriod := timePeriods[e.period]
+	if e.hour >= 0 {
+		hour := e.hour
+		// 下午三点 晚上八点, 时段中的12小时制
+		if hasPeriod && (hour < period.start || hour > period.end) && hour+12 >= period.start && hour+12 <= period.end {
+			hour += 12
+		}
+		minute, second := max0(e.minute), max0(e.second)
+		start = time.Date(year, month, day, hour, minute, second, 0, loc)
+		switch {
+		case e.second >= 0:
+			end = start.Add(time.Second)
+		case e.minute >= 0:
+			end = start.Add(time.Minute)
+		default:
+			end = start.Add(time.Hour)
+		}
+	} else if hasPeriod {
+		start = time.Date(year, month, day, period.start, 0, 0, 0, loc)
+		end = time.Date(year, month, day, period.end, 0, 0, 0, loc)
+	}
+	return TimeRange{Start: start, End: end}, true
+}
+
+func max0(v int) int {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// scanTime 从text[start]开始识别时间表达式, 返回长度和解析结果, 长度为0表示没有识别出
+func scanTime(text []rune, start int) (int, *timeExpr) {
+	if isDigit(text[start]) && !wordBoundary(text, start) {
+		return 0, nil
+	}
+	e := newTimeExpr()
+	if n := e.scanDuration(text, start); n > 0 {
+		return n, e
+	}
+	pos := start
+	if n := e.scanNumericDate(text, pos); n > 0 {
+		pos += n
+		// 2024-03-05 10:30
+		if pos+1 < len(text) && text[pos] == ' ' && isDigit(text[pos+1]) {
+			if n := e.scanClock(text, pos+1); n > 0 {
+				return pos + 1 + n - start, e
+			}
+		}
+	} else {
+		pos += e.scanYear(text, pos)
+		pos += e.scanMonth(text, pos)
+		pos += e.scanDay(text, pos)
+	}
+	if e.period == "" {
+		if period, _, ok := matchTimeWord(text, pos, timePeriods); ok {
+			e.period = period
+			pos += len([]rune(period))
+		}
+	}
+	pos += e.scanClock(text, pos)
+	if pos == start {
+		return 0, nil
+	}
+	return pos - start, e
+}
+
+// matchTimeWord 匹配table中最长的词
+func matchTimeWord[V any](text []rune, pos int, table map[string]V) (string, V, bool) {
+	for l := 4; l > 0; l-- {
+		if pos+l > len(text) {
+			continue
+		}
+		word := string(text[pos : pos+l])
+		if v, ok := table[word]; ok {
+			return word, v, true
+		}
+	}
+	var zero V
+	return "", zero, false
+}
+
+func hasRunePrefix(text []rune, pos int, prefix string) bool {
+	for _, r := range prefix {
+		if pos >= len(text) || text[pos] != r {
+			return false
+		}
+		pos++
+	}
+	return true
+}
+
+// scanTimeNumber 阿拉伯数字或汉字数字, 返回长度、数值以及是否是汉字数字
+func scanTimeNumber(text []rune, pos int) (int, int, bool) {
+	if pos >= len(text) {
+		return 0, 0, false
+	}
+	if isDigit(text[pos]) {
+		n := scanDigits(text, pos)
+		if n > 4 {
+			return 0, 0, false
+		}
+		value, _ := strconv.Atoi(string(text[pos : pos+n]))
+		return n, value, false
+	}
+	n, value := scanChinese(text, pos)
+	return n, int(value), true
+}
+
+// scanDuration 相对时长, 如 3天后 两个月前 十分钟之后
+func (e *timeExpr) scanDuration(text []rune, start int) int {
+	n, value, _ := scanTimeNumber(text, start)
+	if n == 0 || value == 0 {
+		return 0
+	}
+	pos := start + n
+	unit, grain, ok := matchTimeWord(text, pos, durationUnits)
+	if !ok {
+		return 0
+	}
+	pos += len([]rune(unit))
+	direction, sign, ok := matchTimeWord(text, pos, durationDirections)
+	if !ok {
+		return 0
+	}
+	e.durationGrain, e.duration = grain, sign*value
+	return pos + len([]rune(direction)) - start
+}
+
+// scanNumericDate 2024-03-05 2024/3/5
+func (e *timeExpr) scanNumericDate(text []rune, start int) int {
+	if scanDigits(text, start) != 4 || start+4 >= len(text) || (text[start+4] != '-' && text[start+4] != '/') {
+		return 0
+	}
+	sep := text[start+4]
+	year, _ := strconv.Atoi(string(text[start : start+4]))
+	pos := start + 5
+	monthLen := scanDigits(text, pos)
+	if monthLen == 0 || monthLen > 2 || pos+monthLen >= len(text) || text[pos+monthLen] != sep {
+		return 0
+	}
+	month, _ := strconv.Atoi(string(text[pos : pos+monthLen]))
+	pos += monthLen + 1
+	dayLen := scanDigits(text, pos)
+	if dayLen == 0 || dayLen > 2 {
+		return 0
+	}
+	day, _ := strconv.Atoi(string(text[pos : pos+dayLen]))
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0
+	}
+	e.year, e.month, e.day = year, month, day
+	return pos + dayLen - start
+}
+
+// scanYear 2024年 二〇二四年 今年
+func (e *timeExpr) scanYear(text []rune, start int) int {
+	if word, offset, ok := matchTimeWord(text, start, relativeYears); ok {
+		e.yearOffset, e.hasYearOffset = offset, true
+		return len([]rune(word))
+	}
+	n, value, chinese := scanTimeNumber(text, start)
+	if n == 0 || start+n >= len(text) || text[start+n] != '年' {
+		return 0
+	}
+	if chinese {
+		// 逐位读的年份, 避免"三年"这样的时长
+		if n < 3 {
+			return 0
+		}
+		for _, r := range text[start : start+n] {
+			if _, ok := chineseUnits[r]; ok {
+				return 0
+			}
+		}
+	} else if n != 4 {
+		return 0
+	}
+	e.year = value
+	return n + 1
+}
+
+// scanMonth 3月 三月份 下个月
+func (e *timeExpr) scanMonth(text []rune, start int) int {
+	if !e.hasYearOffset && e.year < 0 {
+		if word, offset, ok := matchTimeWord(text, start, relativeMonths); ok {
+			e.monthOffset, e.hasMonthOffset = offset, true
+			return len([]rune(word))
+		}
+	}
+	n, value, _ := scanTimeNumber(text, start)
+	if n == 0 || value < 1 || value > 12 || start+n >= len(text) || text[start+n] != '月' {
+		return 0
+	}
+	e.month = value
+	pos := start + n + 1
+	if pos < len(text) && text[pos] == '份' {
+		pos++
+	}
+	return pos - start
+}
+
+// scanDay 5日 五号 明天 今晚 下周三 星期天
+func (e *timeExpr) scanDay(text []rune, start int) int {
+	n, value, chinese := scanTimeNumber(text, start)
+	if n > 0 && value >= 1 && value <= 31 && start+n < len(text) && (text[start+n] == '日' || text[start+n] == '号') {
+		// 单独的汉字日期如"一日"多是其他意思
+		if !chinese || e.month >= 0 || e.hasMonthOffset {
+			e.day = value
+			return n + 1
+		}
+	}
+	if e.hasDate() {
+		return 0
+	}
+	if word, v, ok := matchTimeWord(text, start, dayPeriods); ok {
+		e.dayOffset, e.hasDayOffset, e.period = v.day, true, v.period
+		return len([]rune(word))
+	}
+	if word, offset, ok := matchTimeWord(text, start, relativeDays); ok {
+		e.dayOffset, e.hasDayOffset = offset, true
+		return len([]rune(word))
+	}
+	pos := start
+	if word, offset, ok := matchTimeWord(text, start, relativeWeeks); ok {
+		e.weekOffset, e.hasWeekOffset = offset, true
+		pos += len([]rune(word))
+	} else {
+		for _, prefix := range weekPrefixes {
+			if hasRunePrefix(text, start, prefix) {
+				pos += len([]rune(prefix))
+				break
+			}
+		}
+		if pos == start {
+			return 0
+		}
+	}
+	if pos < len(text) {
+		if weekday, ok := weekdays[text[pos]]; ok {
+			e.weekday = weekday
+			return pos + 1 - start
+		}
+	}
+	if !e.hasWeekOffset {
+		return 0
+	}
+	return pos - start
+}
+
+// scanClock 10点 十点半 八点一刻 10点05分 10:30 10:30:15
+func (e *timeExpr) scanClock(text []rune, start int) int {
+	n, hour, chinese := scanTimeNumber(text, start)
+	if n == 0 || hour > 24 || start+n >= len(text) {
+		return 0
+	}
+	pos := start + n
+	if !chinese && text[pos] == ':' {
+		minuteLen := scanDigits(text, pos+1)
+		if n > 2 || minuteLen != 2 {
+			return 0
+		}
+		minute, _ := strconv.Atoi(string(text[pos+1 : pos+3]))
+		if minute > 59 {
+			return 0
+		}
+		second := -1
+		pos += 3
+		if pos < len(text) && text[pos] == ':' && scanDigits(text, pos+1) == 2 {
+			second, _ = strconv.Atoi(string(text[pos+1 : pos+3]))
+			if second > 59 {
+				return 0
+			}
+			pos += 3
+		}
+		e.hour, e.minute, e.second = hour, minute, second
+		return pos - start
+	}
+	if text[pos] != '点' && text[pos] != '时' {
+		return 0
+	}
+	pos++
+	minute, second := -1, -1
+	switch {
+	case pos < len(text) && text[pos] == '钟':
+		pos++
+	case pos < len(text) && text[pos] == '半':
+		minute = 30
+		pos++
+	case pos+1 < len(text) && text[pos+1] == '刻' && (text[pos] == '一' || text[pos] == '三'):
+		minute = 15
+		if text[pos] == '三' {
+			minute = 45
+		}
+		pos += 2
+	default:
+		minutePos := pos
+		if minutePos < len(text) && text[minutePos] == '零' {
+			minutePos++
+		}
+		if n, value, _ := scanTimeNumber(text, minutePos); n > 0 && value <= 59 && minutePos+n < len(text) && text[minutePos+n] == '分' {
+			minute = value
+			pos = minutePos + n + 1
+			if n, value, _ := scanTimeNumber(text, pos); n > 0 && value <= 59 && pos+n < len(text) && text[pos+n] == '秒' {
+				second = value
+				pos += n + 1
+			}
+		}
+	}
+	// 单独的汉字钟点如"一点"、"三点"多是其他意思, 需要有日期或时段
+	if chinese && minute < 0 && text[pos-1] != '钟' && !e.hasDate() && e.period == "" {
+		return 0
+	}
+	e.hour, e.minute, e.second = hour, minute, second
+	return pos - start
+}
@@ -0,0 +1,114 @@
+package jieba
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveTime(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	// 2024-03-10 是周日
+	ref := time.Date(2024, 3, 10, 15, 4, 5, 0, loc)
+	layout := "2006-01-02 15:04:05"
+
+	cases := []struct {
+		expr  string
+		start string
+		end   string
+	}{
+		{"2024年3月5日", "2024-03-05 00:00:00", "2024-03-06 00:00:00"},
+		{"二〇二三年", "2023-01-01 00:00:00", "2024-01-01 00:00:00"},
+		{"5月", "2024-05-01 00:00:00", "2024-06-01 00:00:00"},
+		{"27日", "2024-03-27 00:00:00", "2024-03-28 00:00:00"},
+		{"三月五号", "2024-03-05 00:00:00", "2024-03-06 00:00:00"},
+		{"明年2月29日", "", ""},
+		{"2024-02-29 08:30", "2024-02-29 08:30:00", "2024-02-29 08:31:00"},
+		{"下周三上午十点", "2024-03-13 10:00:00", "2024-03-13 11:00:00"},
+		{"周日", "2024-03-10 00:00:00", "2024-03-11 00:00:00"},
+		{"上周", "2024-02-26 00:00:00", "2024-03-04 00:00:00"},
+		{"前天晚上", "2024-03-08 18:00:00", "2024-03-09 00:00:00"},
+		{"今晚八点半", "2024-03-10 20:30:00", "2024-03-10 20:31:00"},
+		{"下午3点15分20秒", "2024-03-10 15:15:20", "2024-03-10 15:15:21"},
+		{"中午一点", "2024-03-10 13:00:00", "2024-03-10 14:00:00"},
+		{"十点零五分", "2024-03-10 10:05:00", "2024-03-10 10:06:00"},
+		{"下个月", "2024-04-01 00:00:00", "2024-05-01 00:00:00"},
+		{"去年12月", "2023-12-01 00:00:00", "2024-01-01 00:00:00"},
+		{"3天后", "2024-03-13 15:04:05", "2024-03-13 15:04:05"},
+		{"两个小时以前", "2024-03-10 13:04:05", "2024-03-10 13:04:05"},
+		// 不是时间
+		{"三年", "", ""},
+		{"一点", "", ""},
+		{"13月", "", ""},
+		{"明天见", "", ""},
+	}
+	for _, c := range cases {
+		r, ok := ResolveTime(c.expr, ref)
+		if c.start == "" {
+			if ok {
+				t.Errorf("%s: expect not resolved, got %v", c.expr, r)
+			}
+			continue
+		}
+		fmt.Println(c.expr, r.Start.Format(layout), r.End.Format(layout))
+		if !ok || r.Start.Format(layout) != c.start || r.End.Format(layout) != c.end {
+			t.Errorf("%s: expect [%s, %s), got %v %v", c.expr, c.start, c.end, r, ok)
+		}
+		if r.Start.Location() != loc {
+			t.Errorf("%s: bad location %v", c.expr, r.Start.Location())
+		}
+	}
+}
+
+// splitTokens 按空格分开的词构造连续的token
+func splitTokens(s string) []*SegToken {
+	var tokens []*SegToken
+	offset := 0
+	for _, word := range strings.Split(s, " ") {
+		l := len([]rune(word))
+		tokens = append(tokens, &SegToken{Word: word, Text: word, Start: offset, End: offset + l})
+		offset += l
+	}
+	return tokens
+}
+
+func TestMergeTime(t *testing.T) {
+	cases := []struct {
+		input  string
+		expect string
+	}{
+		{"会议 在 2024 年 3 月 5 日 举行", "会议 在 [2024年3月5日] 举行"},
+		{"我们 下周 三 上午 十点 见", "我们 [下周三上午十点] 见"},
+		{"RT @laoshipukong : 27日", "RT @laoshipukong : [27日]"},
+		{"他 前天 晚上 来 了", "他 [前天晚上] 来 了"},
+		{"统一 月饼 三 点 建议", "统一 月饼 三 点 建议"},
+		// 结束在token中间时使用较短的表达式
+		{"5 月 5日来", "[5月] 5日来"},
+	}
+	for _, c := range cases {
+		merged := MergeTime(splitTokens(c.input))
+		var words []string
+		for _, token := range merged {
+			if token.Type == TypeTime {
+				words = append(words, "["+token.Word+"]")
+				if token.Pos != PosTime {
+					t.Errorf("bad pos %s", token)
+				}
+			} else {
+				words = append(words, token.Word)
+			}
+		}
+		fmt.Println(merged)
+		if strings.Join(words, " ") != c.expect {
+			t.Errorf("%s: expect %s, got %s", c.input, c.expect, words)
+		}
+	}
+
+	handler := loadTestHandler(t)
+	tokens := MergeTime(handler.SegParagraph("前天晚上10点，他说", ModeSearch))
+	fmt.Println(tokens)
+	if tokens[0].Type != TypeTime || tokens[0].Text != "前天晚上10点" || tokens[0].Start != 0 || tokens[0].ByteEnd != len("前天晚上10点") {
+		t.Errorf("bad tokens %s", tokens)
+	}
+}
@@ -0,0 +1,210 @@
+package jieba
+
+import (
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rolandhe/jiebag/dictload"
+)
+
+const defaultWatchInterval = 10 * time.Second
+
+// WatchOptions DictWatcher的选项
+type WatchOptions struct {
+	// Interval 检查文件修改时间的间隔, 默认10秒
+	Interval time.Duration
+	// Tfidf 同时加载和监控idf词典、停用词, 见NewTfidfFS
+	Tfidf bool
+	// OnReload 每次检测到变化并重新加载后调用, err不为nil时表示加载失败, 继续使用上一次成功加载的词典
+	OnReload func(err error)
+	// Load 加载词典的选项, 默认严格模式, 见dictload.Options
+	Load *dictload.Options
+}
+
+type dictSnapshot struct {
+	handler *SegmentHandler
+	tfidf   Tfidf
+}
+
+// fileStamp 监控的文件的修改时间和大小
+type fileStamp struct {
+	name    string
+	size    int64
+	modTime int64
+}
+
+// DictWatcher 定时检查fsys中的用户词典目录、停用词和idf词典, 变化时重新加载并原子替换.
+// 每次使用时通过Handler、Tfidf获取当前的快照, 正在进行的分词使用旧的快照完成.
+// 重新加载时复用hmm模型, 运行时通过AddWord等修改的词会丢失
+type DictWatcher struct {
+	fsys     fs.FS
+	opts     WatchOptions
+	snapshot atomic.Pointer[dictSnapshot]
+	// reloadLock 保证同一时间只有一个加载, stamps 上一次加载时的文件状态, statFailed 上一次检查时读取文件状态失败
+	reloadLock sync.Mutex
+	stamps     []fileStamp
+	statFailed bool
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// WatchDict 从fsys加载词库并开始监控, fsys的根目录对应dict目录, 第一次加载失败时返回错误
+func WatchDict(fsys fs.FS, opts *WatchOptions) (*DictWatcher, error) {
+	w := &DictWatcher{
+		fsys: fsys,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.Interval <= 0 {
+		w.opts.Interval = defaultWatchInterval
+	}
+
+	stamps, err := w.stat()
+	if err != nil {
+		return nil, err
+	}
+	handler, err := NewSegmentHandlerFSWithOptions(fsys, w.opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := w.load(handler)
+	if err != nil {
+		return nil, err
+	}
+	w.stamps = stamps
+	w.snapshot.Store(snapshot)
+	go w.run()
+	return w, nil
+}
+
+// Handler 当前的handler
+func (w *DictWatcher) Handler() *SegmentHandler {
+	return w.snapshot.Load().handler
+}
+
+// Tfidf 当前的Tfidf, WatchOptions.Tfidf为false时返回nil
+func (w *DictWatcher) Tfidf() Tfidf {
+	return w.snapshot.Load().tfidf
+}
+
+// Reload 不检查修改时间, 立即重新加载, 失败时继续使用旧的词典
+func (w *DictWatcher) Reload() error {
+	return w.reload(true)
+}
+
+// Close 停止监控, 已经获取的handler仍然可以使用
+func (w *DictWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+func (w *DictWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.reload(false)
+		}
+	}
+}
+
+func (w *DictWatcher) reload(force bool) error {
+	w.reloadLock.Lock()
+	defer w.reloadLock.Unlock()
+
+	stamps, err := w.stat()
+	if err != nil {
+		// 文件暂时不可读时只报告一次
+		if w.statFailed && !force {
+			return err
+		}
+		w.statFailed = true
+	} else {
+		w.statFailed = false
+		if !force && slices.Equal(stamps, w.stamps) {
+			return nil
+		}
+		var snapshot *dictSnapshot
+		if snapshot, err = w.rebuild(); err == nil {
+			w.snapshot.Store(snapshot)
+		}
+		// 失败时也记录文件状态, 文件再次修改后才重试
+		w.stamps = stamps
+	}
+	if w.opts.OnReload != nil {
+		w.opts.OnReload(err)
+	}
+	return err
+}
+
+// rebuild 重新加载词典, 复用当前handler的hmm模型
+func (w *DictWatcher) rebuild() (*dictSnapshot, error) {
+	old := w.Handler()
+	trie, err := newDict(w.fsys, w.opts.Load)
+	if err != nil {
+		return nil, err
+	}
+	return w.load(&SegmentHandler{
+		dict:     trie,
+		hmm:      old.hmm,
+		posHmm:   old.posHmm,
+		loadOpts: w.opts.Load,
+	})
+}
+
+func (w *DictWatcher) load(handler *SegmentHandler) (*dictSnapshot, error) {
+	snapshot := &dictSnapshot{handler: handler}
+	if w.opts.Tfidf {
+		tfidf, err := NewTfidfFSWithOptions(w.fsys, handler, w.opts.Load)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.tfidf = tfidf
+	}
+	return snapshot, nil
+}
+
+// stat 监控的文件的状态: 用户词典目录下的所有文件, 以及开启Tfidf时的idf词典和停用词
+func (w *DictWatcher) stat() ([]fileStamp, error) {
+	var stamps []fileStamp
+	add := func(name string, info fs.FileInfo) {
+		stamps = append(stamps, fileStamp{name: name, size: info.Size(), modTime: info.ModTime().UnixNano()})
+	}
+	err := fs.WalkDir(w.fsys, UserDictDirName, func(fp string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		add(fp, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.opts.Tfidf {
+		for _, name := range []string{IdfDictName, IdfStopWordsName} {
+			info, err := fs.Stat(w.fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			add(name, info)
+		}
+	}
+	return stamps, nil
+}
@@ -0,0 +1,108 @@
+package jieba
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDictWatcher(t *testing.T) {
+	dir := t.TempDir()
+	copyFile := func(src, dst string) {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, dst), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, UserDictDirName), 0755); err != nil {
+		t.Fatal(err)
+	}
+	copyFile("../test/jieba/dict.txt", BaseDictName)
+	copyFile("../dict/prob_emit.txt", BaseProbName)
+	copyFile("../dict/idf_stop_words.txt", IdfStopWordsName)
+	copyFile("../test/jieba/user/user.dict", UserDictDirName+"/user.dict")
+	if err := os.WriteFile(filepath.Join(dir, IdfDictName), []byte("北京 5.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan error, 10)
+	watcher, err := WatchDict(os.DirFS(dir), &WatchOptions{
+		Interval: 10 * time.Millisecond,
+		Tfidf:    true,
+		OnReload: func(err error) { reloaded <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	seg := func(h *SegmentHandler) string {
+		return strings.Join(tokenWords(h.SegParagraphWithOptions("杭研大厦", &SegOptions{DisableHmm: true})), "/")
+	}
+	// 修改时间的精度可能是秒, 每次写文件时设置不同的修改时间
+	mtime := time.Now()
+	writeUserDict := func(content string) {
+		fp := filepath.Join(dir, UserDictDirName, "tenant.dict")
+		if err := os.WriteFile(fp, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime = mtime.Add(time.Minute)
+		if err := os.Chtimes(fp, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wait := func() error {
+		select {
+		case err := <-reloaded:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("reload timeout")
+			return nil
+		}
+	}
+
+	old := watcher.Handler()
+	if got := seg(old); got != "杭研/大厦" {
+		t.Fatalf("bad seg %s", got)
+	}
+	writeUserDict("杭研大厦 100 nt\n")
+	if err = wait(); err != nil {
+		t.Fatal(err)
+	}
+	current := watcher.Handler()
+	if got := seg(current); got != "杭研大厦" {
+		t.Errorf("bad seg after reload %s", got)
+	}
+	// 旧的快照不变
+	if got := seg(old); got != "杭研/大厦" {
+		t.Errorf("old snapshot changed %s", got)
+	}
+	if watcher.Tfidf() == nil || len(watcher.Tfidf().TopNByString("杭研大厦", 1)) != 1 {
+		t.Error("bad tfidf")
+	}
+
+	// 加载失败时继续使用上一次的词典
+	writeUserDict("杭研大厦 100 nt extra\n")
+	if err = wait(); err == nil {
+		t.Error("expect reload error")
+	}
+	if watcher.Handler() != current {
+		t.Error("handler replaced after failed reload")
+	}
+
+	writeUserDict("")
+	if err = wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := seg(watcher.Handler()); got != "杭研/大厦" {
+		t.Errorf("bad seg after removing word %s", got)
+	}
+	if err = watcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+package opencc
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+// 转换配置, 同OpenCC的配置文件名
+const (
+	S2T   = "s2t"   // 简体到繁体
+	T2S   = "t2s"   // 繁体到简体
+	S2TW  = "s2tw"  // 简体到台湾正体
+	TW2S  = "tw2s"  // 台湾正体到简体
+	S2TWP = "s2twp" // 简体到台湾正体, 并转换常用词汇, 如 鼠标->滑鼠
+	TW2SP = "tw2sp" // 台湾正体到简体, 并转换常用词汇
+	S2HK  = "s2hk"  // 简体到香港繁体
+	HK2S  = "hk2s"  // 香港繁体到简体
+	T2TW  = "t2tw"  // 繁体到台湾正体
+	TW2T  = "tw2t"  // 台湾正体到繁体
+	T2HK  = "t2hk"  // 繁体到香港繁体
+	HK2T  = "hk2t"  // 香港繁体到繁体
+)
+
+const (
+	STCharacters         = "STCharacters.txt"
+	STPhrases            = "STPhrases.txt"
+	TSCharacters         = "TSCharacters.txt"
+	TSPhrases            = "TSPhrases.txt"
+	TWVariants           = "TWVariants.txt"
+	TWVariantsRevPhrases = "TWVariantsRevPhrases.txt"
+	TWPhrases            = "TWPhrases.txt"
+	HKVariants           = "HKVariants.txt"
+	HKVariantsRevPhrases = "HKVariantsRevPhrases.txt"
+)
+
+// dictRef 配置中引用的词典, reverse表示使用反转后的词典, 即OpenCC中的*Rev词典
+type dictRef struct {
+	name    string
+	reverse bool
+}
+
+var (
+	s2tStep  = []dictRef{{name: STPhrases}, {name: STCharacters}}
+	t2sStep  = []dictRef{{name: TSPhrases}, {name: TSCharacters}}
+	tw2tStep = []dictRef{{name: TWVariantsRevPhrases}, {name: TWVariants, reverse: true}}
+	hk2tStep = []dictRef{{name: HKVariantsRevPhrases}, {name: HKVariants, reverse: true}}
+)
+
+var configs = map[string][][]dictRef{
+	S2T:   {s2tStep},
+	T2S:   {t2sStep},
+	S2TW:  {s2tStep, {{name: TWVariants}}},
+	TW2S:  {tw2tStep, t2sStep},
+	S2TWP: {s2tStep, {{name: TWPhrases}}, {{name: TWVariants}}},
+	TW2SP: {append([]dictRef{{name: TWPhrases, reverse: true}}, tw2tStep...), t2sStep},
+	S2HK:  {s2tStep, {{name: HKVariants}}},
+	HK2S:  {hk2tStep, t2sStep},
+	T2TW:  {{{name: TWVariants}}},
+	TW2T:  {tw2tStep},
+	T2HK:  {{{name: HKVariants}}},
+	HK2T:  {hk2tStep},
+}
+
+// splitDicts OpenCC源码中TWPhrases分成了多个文件, 编译时才合并, 没有合并后的文件时加载这些文件
+var splitDicts = map[string][]string{
+	TWPhrases: {"TWPhrasesIT.txt", "TWPhrasesName.txt", "TWPhrasesOther.txt"},
+}
+
+//go:embed data
+var dataFS embed.FS
+
+var defaultLoader = struct {
+	sync.Mutex
+	*loader
+	converters map[string]*Converter
+}{
+	converters: map[string]*Converter{},
+}
+
+// Default 使用内置词典创建转换器, 同一个配置返回同一个转换器
+func Default(config string) (*Converter, error) {
+	defaultLoader.Lock()
+	defer defaultLoader.Unlock()
+	if c, ok := defaultLoader.converters[config]; ok {
+		return c, nil
+	}
+	if defaultLoader.loader == nil {
+		sub, err := fs.Sub(dataFS, "data")
+		if err != nil {
+			return nil, err
+		}
+		defaultLoader.loader = newLoader(sub)
+	}
+	c, err := defaultLoader.converter(config)
+	if err != nil {
+		return nil, err
+	}
+	defaultLoader.converters[config] = c
+	return c, nil
+}
+
+// New 从fsys加载OpenCC文本格式的词典创建转换器, fsys通常是OpenCC源码的data/dictionary目录
+func New(fsys fs.FS, config string) (*Converter, error) {
+	return newLoader(fsys).converter(config)
+}
+
+type loader struct {
+	fsys  fs.FS
+	dicts map[dictRef]*Dict
+}
+
+func newLoader(fsys fs.FS) *loader {
+	return &loader{
+		fsys:  fsys,
+		dicts: map[dictRef]*Dict{},
+	}
+}
+
+func (l *loader) converter(config string) (*Converter, error) {
+	refs, ok := configs[config]
+	if !ok {
+		return nil, errors.New("unknown config:" + config)
+	}
+	chain := make([][]*Dict, 0, len(refs))
+	for _, step := range refs {
+		group := make([]*Dict, 0, len(step))
+		for _, ref := range step {
+			d, err := l.load(ref)
+			if err != nil {
+				return nil, err
+			}
+			group = append(group, d)
+		}
+		chain = append(chain, group)
+	}
+	return NewConverter(chain...), nil
+}
+
+func (l *loader) load(ref dictRef) (*Dict, error) {
+	if d, ok := l.dicts[ref]; ok {
+		return d, nil
+	}
+	var d *Dict
+	var err error
+	if ref.reverse {
+		var base *Dict
+		if base, err = l.load(dictRef{name: ref.name}); err != nil {
+			return nil, err
+		}
+		d, err = base.Reverse()
+	} else {
+		var entries map[string][]string
+		if entries, err = l.readEntries(ref.name); err != nil {
+			return nil, err
+		}
+		d, err = NewDict(entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.dicts[ref] = d
+	return d, nil
+}
+
+func (l *loader) readEntries(name string) (map[string][]string, error) {
+	entries, err := readEntries(l.fsys, name)
+	parts, ok := splitDicts[name]
+	if err == nil || !errors.Is(err, fs.ErrNotExist) || !ok {
+		return entries, err
+	}
+	entries = map[string][]string{}
+	for _, part := range parts {
+		partEntries, err := readEntries(l.fsys, part)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range partEntries {
+			if _, ok := entries[key]; !ok {
+				entries[key] = values
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,81 @@
+// Package opencc 简繁转换, 词典格式和转换配置同OpenCC(https://github.com/BYVoid/OpenCC).
+//
+// 转换由多个步骤串联, 每个步骤是一组词典, 从左到右按最长匹配替换, 多个词典匹配长度相同时使用靠前的词典.
+// 内置的词典只包含常用字和少量词组, 完整的转换需要使用OpenCC的词典文件, 见New.
+package opencc
+
+import "unicode/utf8"
+
+type Converter struct {
+	chain [][]*Dict
+}
+
+// NewConverter 创建转换器, chain中每一项是一个转换步骤使用的词典组
+func NewConverter(chain ...[]*Dict) *Converter {
+	return &Converter{chain: chain}
+}
+
+func (c *Converter) Convert(s string) string {
+	dst, _, _ := c.ConvertRunes([]rune(s))
+	return string(dst)
+}
+
+// ConvertRunes 转换src, 同时返回dst中每个字符在src中的区间, dst[i]由src[starts[i]:ends[i]]转换得到.
+// 替换前后长度相同时逐字对应, 否则替换结果中的每个字符都对应整个被替换的片段
+func (c *Converter) ConvertRunes(src []rune) (dst []rune, starts, ends []int) {
+	dst = src
+	starts = make([]int, len(src))
+	ends = make([]int, len(src))
+	for i := range src {
+		starts[i] = i
+		ends[i] = i + 1
+	}
+	for _, group := range c.chain {
+		dst, starts, ends = convertStep(group, dst, starts, ends)
+	}
+	return dst, starts, ends
+}
+
+func convertStep(group []*Dict, src []rune, srcStarts, srcEnds []int) ([]rune, []int, []int) {
+	dst := make([]rune, 0, len(src))
+	starts := make([]int, 0, len(src))
+	ends := make([]int, 0, len(src))
+	for i := 0; i < len(src); {
+		length, value := matchGroup(group, src[i:])
+		if length == 0 {
+			dst = append(dst, src[i])
+			starts = append(starts, srcStarts[i])
+			ends = append(ends, srcEnds[i])
+			i++
+			continue
+		}
+		if utf8.RuneCountInString(value) == length {
+			j := i
+			for _, r := range value {
+				dst = append(dst, r)
+				starts = append(starts, srcStarts[j])
+				ends = append(ends, srcEnds[j])
+				j++
+			}
+		} else {
+			start, end := srcStarts[i], srcEnds[i+length-1]
+			for _, r := range value {
+				dst = append(dst, r)
+				starts = append(starts, start)
+				ends = append(ends, end)
+			}
+		}
+		i += length
+	}
+	return dst, starts, ends
+}
+
+func matchGroup(group []*Dict, src []rune) (int, string) {
+	best, bestValue := 0, ""
+	for _, d := range group {
+		if length, value, ok := d.MatchPrefix(src); ok && length > best {
+			best, bestValue = length, value
+		}
+	}
+	return best, bestValue
+}
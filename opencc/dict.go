@@ -0,0 +1,128 @@
+package opencc
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/rolandhe/jiebag/internal/datrie"
+)
+
+// Dict 转换词典, 每个key对应一个或多个候选, 转换时使用第一个候选
+type Dict struct {
+	da     *datrie.DoubleArray
+	keys   [][]rune
+	values [][]string
+}
+
+// NewDict 从key到候选的映射创建词典
+func NewDict(entries map[string][]string) (*Dict, error) {
+	keys := make([][]rune, 0, len(entries))
+	for key, values := range entries {
+		if len(key) == 0 || len(values) == 0 {
+			return nil, errors.New("empty dict entry:" + key)
+		}
+		keys = append(keys, []rune(key))
+	}
+	slices.SortFunc(keys, slices.Compare[[]rune])
+	da, err := datrie.Build(keys)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dict{
+		da:     da,
+		keys:   keys,
+		values: make([][]string, len(keys)),
+	}
+	for i, key := range keys {
+		d.values[i] = entries[string(key)]
+	}
+	return d, nil
+}
+
+// LoadDict 加载OpenCC文本格式的词典, 每行: key<TAB>候选1 候选2 ...
+func LoadDict(fsys fs.FS, name string) (*Dict, error) {
+	entries, err := readEntries(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewDict(entries)
+}
+
+func readEntries(fsys fs.FS, name string) (map[string][]string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := map[string][]string{}
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		items := strings.SplitN(line, "\t", 2)
+		if len(items) != 2 {
+			return nil, errors.New("bad items:" + line)
+		}
+		values := strings.Fields(items[1])
+		if len(values) == 0 {
+			return nil, errors.New("bad items:" + line)
+		}
+		// 同一个key出现多次时以第一次为准, 同OpenCC
+		if _, ok := entries[items[0]]; !ok {
+			entries[items[0]] = values
+		}
+	}
+	return entries, scan.Err()
+}
+
+// Reverse 反转词典, 每个候选作为key, 对应原来的key, 用于生成OpenCC的*Rev词典
+func (d *Dict) Reverse() (*Dict, error) {
+	entries := map[string][]string{}
+	for i, key := range d.keys {
+		for _, value := range d.values[i] {
+			if !slices.Contains(entries[value], string(key)) {
+				entries[value] = append(entries[value], string(key))
+			}
+		}
+	}
+	return NewDict(entries)
+}
+
+// Lookup 精确查找key的所有候选
+func (d *Dict) Lookup(key string) ([]string, bool) {
+	id, ok := d.da.Find(key)
+	if !ok {
+		return nil, false
+	}
+	return d.values[id], true
+}
+
+// MatchPrefix 查找src的最长前缀, 返回前缀长度和第一个候选
+func (d *Dict) MatchPrefix(src []rune) (int, string, bool) {
+	var state int32
+	length, id := 0, int32(-1)
+	for i, r := range src {
+		var ok bool
+		if state, ok = d.da.Child(state, r); !ok {
+			break
+		}
+		if v, ok := d.da.ValueOf(state); ok {
+			length, id = i+1, v
+		}
+	}
+	if id < 0 {
+		return 0, "", false
+	}
+	return length, d.values[id][0], true
+}
+
+// Len 词典中key的个数
+func (d *Dict) Len() int {
+	return len(d.keys)
+}
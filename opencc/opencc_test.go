@@ -0,0 +1,84 @@
+package opencc
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDefault(t *testing.T) {
+	cases := []struct {
+		config string
+		input  string
+		expect string
+	}{
+		{T2S, "電話 醫院 夢之聲 熱線 訂票 改簽", "电话 医院 梦之声 热线 订票 改签"},
+		{S2T, "头发 发展 以后 皇后 面条 面试 干净 干部", "頭髮 發展 以後 皇后 麵條 面試 乾淨 幹部"},
+		{S2TW, "为什么在这里着急", "為什麼在這裡著急"},
+		{S2TWP, "我的鼠标和软件", "我的滑鼠和軟體"},
+		{TW2S, "這裡顯著著急", "这里显著着急"},
+		{TW2SP, "我的滑鼠和軟體", "我的鼠标和软件"},
+		{S2HK, "说明税务", "説明税務"},
+		{HK2S, "説明税務", "说明税务"},
+		{T2S, "乾隆乾杯", "乾隆干杯"},
+	}
+	for _, c := range cases {
+		converter, err := Default(c.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := converter.Convert(c.input)
+		fmt.Println(c.config, c.input, got)
+		if got != c.expect {
+			t.Errorf("%s %s: expect %s, got %s", c.config, c.input, c.expect, got)
+		}
+	}
+	if _, err := Default("x2y"); err == nil {
+		t.Errorf("expect unknown config error")
+	}
+}
+
+func TestConvertRunes(t *testing.T) {
+	fsys := fstest.MapFS{
+		STCharacters: {Data: []byte("软\t軟\n件\t件\n")},
+		STPhrases:    {Data: []byte("# comment\n")},
+		TWVariants:   {Data: []byte("")},
+		// 长度变化的替换
+		"TWPhrasesIT.txt":    {Data: []byte("軟件\t軟體\nU盤\t隨身碟\n")},
+		"TWPhrasesName.txt":  {Data: []byte("")},
+		"TWPhrasesOther.txt": {Data: []byte("")},
+	}
+	converter, err := New(fsys, S2TWP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, starts, ends := converter.ConvertRunes([]rune("买软件U盤"))
+	if string(dst) != "买軟體隨身碟" {
+		t.Fatalf("bad convert %s", string(dst))
+	}
+	if fmt.Sprint(starts, ends) != "[0 1 2 3 3 3] [1 2 3 5 5 5]" {
+		t.Errorf("bad spans %v %v", starts, ends)
+	}
+
+	if _, err = New(fstest.MapFS{STCharacters: {Data: []byte("bad line\n")}, STPhrases: {}}, S2T); err == nil {
+		t.Errorf("expect bad dict error")
+	}
+}
+
+func TestReverse(t *testing.T) {
+	d, err := NewDict(map[string][]string{"台": {"臺", "颱", "台"}, "后": {"後", "后"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev, err := d.Reverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, ok := rev.Lookup("颱")
+	if !ok || fmt.Sprint(values) != "[台]" {
+		t.Errorf("bad reverse %v", values)
+	}
+	if length, value, ok := rev.MatchPrefix([]rune("後來")); !ok || length != 1 || value != "后" {
+		t.Errorf("bad match %d %s", length, value)
+	}
+}
@@ -0,0 +1,115 @@
+package pinyin
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/rolandhe/jiebag/internal/binfmt"
+	"github.com/rolandhe/jiebag/internal/datrie"
+)
+
+const (
+	binaryMagic = "JIEBAGPY"
+	// BinaryVersion 拼音词典二进制格式的版本, 格式变化时递增
+	BinaryVersion = 2
+
+	secDatBase     = "dat.base"
+	secDatCheck    = "dat.check"
+	secDatValue    = "dat.value"
+	secDatCodes    = "dat.codes"
+	secDatExtRunes = "dat.ext_runes"
+	secDatExtCodes = "dat.ext_codes"
+	secPinyin      = "pinyin"
+)
+
+// MarshalBinary 把拼音词典编译成二进制, 用UnmarshalBinary加载, 加载时不需要再解析文本词典
+func (root *DictNode) MarshalBinary() ([]byte, error) {
+	da := root.da
+	if da == nil {
+		var err error
+		if da, err = datrie.Build(nil); err != nil {
+			return nil, err
+		}
+	}
+	w := binfmt.NewWriter(binaryMagic, BinaryVersion)
+	w.Add(secDatBase, binfmt.Int32Bytes(da.Base))
+	w.Add(secDatCheck, binfmt.Int32Bytes(da.Check))
+	w.Add(secDatValue, binfmt.Int32Bytes(da.Value))
+	w.Add(secDatCodes, binfmt.Uint32Bytes(da.Codes))
+	w.Add(secDatExtRunes, binfmt.Uint32Bytes(da.ExtRunes))
+	w.Add(secDatExtCodes, binfmt.Uint32Bytes(da.ExtCodes))
+
+	var enc binfmt.Encoder
+	enc.PutUvarint(uint64(len(root.pinyin)))
+	for _, wp := range root.pinyin {
+		enc.PutUvarint(uint64(len(wp.group)))
+		for _, group := range wp.group {
+			enc.PutUvarint(uint64(len(group)))
+			for _, sp := range group {
+				enc.PutString(sp.word)
+				enc.PutUvarint(uint64(sp.tone))
+			}
+		}
+	}
+	w.Add(secPinyin, enc.Bytes())
+	return w.Bytes()
+}
+
+// UnmarshalBinary 加载MarshalBinary的结果, 会拷贝data中的数据
+func (root *DictNode) UnmarshalBinary(data []byte) error {
+	f, err := binfmt.Parse(data, binaryMagic)
+	if err != nil {
+		return err
+	}
+	if f.Version != BinaryVersion {
+		return fmt.Errorf("pinyin dict version %d, expect %d", f.Version, BinaryVersion)
+	}
+	names := []string{secDatBase, secDatCheck, secDatValue, secDatCodes, secDatExtRunes, secDatExtCodes, secPinyin}
+	secs := make([][]byte, len(names))
+	for i, name := range names {
+		if secs[i], err = f.MustSection(name); err != nil {
+			return err
+		}
+	}
+	node := DictNode{
+		da: &datrie.DoubleArray{
+			Base:     slices.Clone(binfmt.Int32s(secs[0])),
+			Check:    slices.Clone(binfmt.Int32s(secs[1])),
+			Value:    slices.Clone(binfmt.Int32s(secs[2])),
+			Codes:    slices.Clone(binfmt.Uint32s(secs[3])),
+			ExtRunes: slices.Clone(binfmt.Uint32s(secs[4])),
+			ExtCodes: slices.Clone(binfmt.Uint32s(secs[5])),
+		},
+	}
+
+	dec := binfmt.NewDecoder(secs[6])
+	words := dec.Len()
+	node.pinyin = make([]*wordPinyin, 0, words)
+	for i := 0; i < words && dec.Err() == nil; i++ {
+		wp := &wordPinyin{}
+		groups := dec.Len()
+		for j := 0; j < groups && dec.Err() == nil; j++ {
+			l := dec.Len()
+			group := make([]*singlePinyin, 0, l)
+			for k := 0; k < l && dec.Err() == nil; k++ {
+				group = append(group, &singlePinyin{
+					word: dec.String(),
+					tone: uint8(dec.Uvarint()),
+				})
+			}
+			wp.group = append(wp.group, group)
+		}
+		if len(wp.group) == 0 {
+			return binfmt.ErrBadFormat
+		}
+		node.pinyin = append(node.pinyin, wp)
+	}
+	if err = dec.Err(); err != nil {
+		return err
+	}
+	if err = node.da.Validate(len(node.pinyin)); err != nil {
+		return err
+	}
+	*root = node
+	return nil
+}
@@ -1,12 +1,17 @@
 package pinyin
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/rolandhe/jiebag/dictload"
+	"github.com/rolandhe/jiebag/internal/datrie"
 )
 
 var unicodeToneMap = map[uint8][]rune{}
@@ -101,76 +106,49 @@ func (wp *wordPinyin) getFirstString(formatter PinyinFmt) []string {
 	return ret
 }
 
+// DictNode 拼音词典, 使用双数组trie, 词的拼音按trie中词的序号存储
 type DictNode struct {
-	children map[rune]*DictNode
-	isWord   bool
-	pinyin   *wordPinyin
+	da     *datrie.DoubleArray
+	pinyin []*wordPinyin
 }
 
-func (root *DictNode) addSingle(r rune, pys []string) *DictNode {
+// dictBuilder 加载文本词典时收集每个词的拼音, 加载完成后构建DictNode
+type dictBuilder map[string]*wordPinyin
+
+func (b dictBuilder) addSingle(r rune, pys []string) {
 	wp := &wordPinyin{}
 	wp.add(pys)
-
-	child := &DictNode{
-		isWord: true,
-		pinyin: wp,
-	}
-	return root.addChild(r, child)
+	b[string(r)] = wp
 }
 
-func (root *DictNode) addChild(r rune, child *DictNode) *DictNode {
-	if root.children == nil {
-		root.children = map[rune]*DictNode{}
+// addWord 多音字词典中的词, 已经存在的词不覆盖
+func (b dictBuilder) addWord(word string, pys []string) {
+	if utf8.RuneCountInString(word) != len(pys) {
+		return
 	}
-	root.children[r] = child
-	return child
-}
-
-func (root *DictNode) addWord(word string, pys []string) {
-	runes := []rune(word)
-	l := len(runes)
-	if l != len(pys) {
+	if _, ok := b[word]; ok {
 		return
 	}
+	wp := &wordPinyin{}
+	wp.addWordGroup(pys)
+	b[word] = wp
+}
 
-	af := func(p *DictNode, r rune, group []string, isWord bool) *DictNode {
-		var wp *wordPinyin
-		if isWord {
-			wp = &wordPinyin{}
-			wp.addWordGroup(group)
-		}
-		child := &DictNode{
-			isWord: isWord,
-			pinyin: wp,
-		}
-		return p.addChild(r, child)
+func (b dictBuilder) build() (*DictNode, error) {
+	keys := make([][]rune, 0, len(b))
+	for word := range b {
+		keys = append(keys, []rune(word))
 	}
-
-	var isWord bool
-	var wordsPys []string
-	p := root
-	for i, r := range runes {
-		if l-1 == i {
-			isWord = true
-			wordsPys = pys
-		}
-		if !p.hasChildren() {
-			p = af(p, r, wordsPys, isWord)
-			continue
-		}
-		child, ok := p.children[r]
-		if !ok {
-			p = af(p, r, wordsPys, isWord)
-			continue
-		}
-		if isWord && !child.isWord {
-			child.isWord = isWord
-			wp := &wordPinyin{}
-			wp.addWordGroup(pys)
-			child.pinyin = wp
-		}
-		p = child
+	slices.SortFunc(keys, slices.Compare[[]rune])
+	da, err := datrie.Build(keys)
+	if err != nil {
+		return nil, err
+	}
+	node := &DictNode{da: da, pinyin: make([]*wordPinyin, len(keys))}
+	for i, key := range keys {
+		node.pinyin[i] = b[string(key)]
 	}
+	return node, nil
 }
 
 func (root *DictNode) ConvertString(next string, formatter PinyinFmt) []string {
@@ -205,18 +183,14 @@ func (root *DictNode) Convert(next []rune, formatter PinyinFmt) []string {
 	return ret
 }
 
-func (root *DictNode) hasChildren() bool {
-	return len(root.children) > 0
-}
-
 func (root *DictNode) matchFirst(next []rune) (*wordPinyin, []rune, int) {
-	p := root
+	var state int32
 	var candidate *wordPinyin
 	var nextIndex int
 	var startIndex int
 	for i, r := range next {
-		child := p.matchChild(r)
-		if child == nil {
+		child, ok := root.child(state, r)
+		if !ok {
 			if candidate == nil {
 				nextIndex = i + 1
 				startIndex = i + 1
@@ -225,11 +199,11 @@ func (root *DictNode) matchFirst(next []rune) (*wordPinyin, []rune, int) {
 			break
 		}
 
-		if child.isWord {
-			candidate = child.pinyin
+		if word, isWord := root.da.ValueOf(child); isWord {
+			candidate = root.pinyin[word]
 			nextIndex = i + 1
 		}
-		p = child
+		state = child
 	}
 	if nextIndex == len(next) {
 		next = nil
@@ -239,50 +213,92 @@ func (root *DictNode) matchFirst(next []rune) (*wordPinyin, []rune, int) {
 	return candidate, next, startIndex
 }
 
-func (root *DictNode) matchChild(r rune) *DictNode {
-	if !root.hasChildren() {
-		return nil
+func (root *DictNode) child(state int32, r rune) (int32, bool) {
+	if root.da == nil {
+		return 0, false
 	}
-	return root.children[r]
+	return root.da.Child(state, r)
 }
 
+const (
+	PinyinDictName    = "pinyin.txt"
+	PolyphoneDictName = "polyphone.txt"
+	AlphabetDictName  = "pinyin_alphabet.txt"
+)
+
 func LoadDict(rootPath string) (*DictNode, error) {
-	node := &DictNode{}
+	return LoadDictFS(os.DirFS(rootPath))
+}
 
-	if err := loadFile(path.Join(rootPath, "pinyin.txt"), func(line string) {
-		items := strings.Split(line, "=")
-		pinyins := strings.Split(strings.TrimSpace(items[1]), ",")
-		w := strings.TrimSpace(items[0])
-		node.addSingle([]rune(w)[0], pinyins)
+// LoadDictFS 从fsys加载拼音词典, fsys的根目录对应dict/pinyin目录
+func LoadDictFS(fsys fs.FS) (*DictNode, error) {
+	return LoadDictFSWithOptions(fsys, nil)
+}
+
+// LoadDictFSWithOptions 同LoadDictFS, 通过opts选择严格或宽松模式, 见dictload.Options
+func LoadDictFSWithOptions(fsys fs.FS, opts *dictload.Options) (*DictNode, error) {
+	builder := dictBuilder{}
+
+	if _, err := dictload.Load(fsys, PinyinDictName, opts, func(line string) error {
+		w, pinyins, err := splitPinyinLine(line, ",")
+		if err != nil {
+			return err
+		}
+		runes := []rune(w)
+		if len(runes) != 1 {
+			return errors.New("not single char:" + w)
+		}
+		builder.addSingle(runes[0], pinyins)
+		return nil
 	}); err != nil {
 		return nil, err
 	}
 
-	if err := loadFile(path.Join(rootPath, "polyphone.txt"), func(line string) {
-		items := strings.Split(line, "=")
-		pinyins := strings.Fields(items[1])
-		node.addWord(strings.TrimSpace(items[0]), pinyins)
+	if _, err := dictload.Load(fsys, PolyphoneDictName, opts, func(line string) error {
+		w, pinyins, err := splitPinyinLine(line, "")
+		if err != nil {
+			return err
+		}
+		if utf8.RuneCountInString(w) != len(pinyins) {
+			return fmt.Errorf("%d pinyin for %s", len(pinyins), w)
+		}
+		builder.addWord(w, pinyins)
+		return nil
 	}); err != nil {
 		return nil, err
 	}
 
-	return node, nil
+	return builder.build()
 }
 
-func loadFile(filePath string, acceptor func(line string)) error {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return err
+// splitPinyinLine 解析 词=拼音, sep为空时拼音以空白分隔, 空行返回dictload.ErrSkip
+func splitPinyinLine(line string, sep string) (string, []string, error) {
+	if strings.TrimSpace(line) == "" {
+		return "", nil, dictload.ErrSkip
 	}
-	defer f.Close()
-
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		line := scan.Text()
-		acceptor(line)
+	w, py, ok := strings.Cut(line, "=")
+	if !ok {
+		return "", nil, errors.New("missing =:" + line)
 	}
-
-	return scan.Err()
+	w = strings.TrimSpace(w)
+	if w == "" {
+		return "", nil, errors.New("empty word:" + line)
+	}
+	var pinyins []string
+	if sep == "" {
+		pinyins = strings.Fields(py)
+	} else {
+		pinyins = strings.Split(strings.TrimSpace(py), sep)
+	}
+	for _, p := range pinyins {
+		if strings.TrimSpace(p) == "" {
+			return "", nil, errors.New("empty pinyin:" + line)
+		}
+	}
+	if len(pinyins) == 0 {
+		return "", nil, errors.New("empty pinyin:" + line)
+	}
+	return w, pinyins, nil
 }
 
 // unicodeTone Algorithm from nlp-lang java project, see pinyin.PinyinFormatter class
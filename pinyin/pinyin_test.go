@@ -2,7 +2,14 @@ package pinyin
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/dictload"
 )
 
 func TestConvert(t *testing.T) {
@@ -22,3 +29,88 @@ func TestUnicodeTone(t *testing.T) {
 	utone := unicodeTone("biao", 3)
 	fmt.Println(utone)
 }
+
+func TestMarshalBinary(t *testing.T) {
+	node, err := LoadDict("../dict/pinyin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := node.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &DictNode{}
+	if err = loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	sentence := "a河北乐亭核心目标a与，，，，，，,@#$%^&*(发展战略都市绿"
+	for _, formatter := range []PinyinFmt{WithoutTone, ToneTail, UnicodeWithTone} {
+		expect := fmt.Sprint(node.ConvertString(sentence, formatter))
+		if got := fmt.Sprint(loaded.ConvertString(sentence, formatter)); got != expect {
+			t.Errorf("expect %s, got %s", expect, got)
+		}
+	}
+}
+
+func TestLoadBadLine(t *testing.T) {
+	fsys := fstest.MapFS{
+		PinyinDictName:    &fstest.MapFile{Data: []byte("一=yi1\n丁\n行=xing2,hang2\n=a1\n")},
+		PolyphoneDictName: &fstest.MapFile{Data: []byte("行货=huo4\n银行=yin2 hang2\n")},
+		AlphabetDictName:  &fstest.MapFile{Data: []byte("a\nai\na\nA1\nlü\n")},
+	}
+	_, err := LoadDictFS(fsys)
+	fmt.Println(err)
+	if err == nil || err.Error() != "pinyin.txt:2: missing =:丁" {
+		t.Errorf("bad strict error %v", err)
+	}
+
+	opts := &dictload.Options{Lenient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	node, err := LoadDictFSWithOptions(fsys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(node.ConvertString("一行银行", ToneTail)); got != "[yi1 xing2 yin2 hang2]" {
+		t.Errorf("bad convert %s", got)
+	}
+
+	if _, err = LoadGuessFS(fsys); err == nil || err.Error() != "pinyin_alphabet.txt:4: bad pinyin:A1" {
+		t.Errorf("bad strict error %v", err)
+	}
+	guess, err := LoadGuessFSWithOptions(fsys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(guess.Guess("aialü")); got != "[ai a lü]" {
+		t.Errorf("bad guess %s", got)
+	}
+
+	// 仓库中的字母表有ü
+	if _, err = LoadGuess("../dict/pinyin"); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkConvert(b *testing.B) {
+	node, err := LoadDict("../dict/pinyin")
+	if err != nil {
+		b.Fatal(err)
+	}
+	sentence := []rune(strings.Repeat("码完代码，他起身关上电脑，用滚烫的开水为自己泡制一碗腾着热气的老坛酸菜面。", 20))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		node.Convert(sentence, ToneTail)
+	}
+}
+
+func BenchmarkMemoryDict(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		node, _ := LoadDict("../dict/pinyin")
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapInuse-before.HeapInuse)/(1<<20), "MB")
+		runtime.KeepAlive(node)
+	}
+}
@@ -1,12 +1,15 @@
 package pinyin
 
 import (
-	"bufio"
-	"log"
+	"errors"
+	"io/fs"
 	"math"
 	"os"
-	"path"
+	"slices"
 	"strings"
+
+	"github.com/rolandhe/jiebag/dictload"
+	"github.com/rolandhe/jiebag/internal/datrie"
 )
 
 type segment struct {
@@ -19,9 +22,9 @@ type segTokenInternal struct {
 	count int
 }
 
+// PureGuessNode 拼音字母表, 使用双数组trie, 用于把连续的拼音字母切分成音节
 type PureGuessNode struct {
-	children map[uint8]*PureGuessNode
-	wordEnd  bool
+	da *datrie.DoubleArray
 }
 
 func (node *PureGuessNode) Guess(stmt string) []string {
@@ -80,7 +83,6 @@ func calc(stat []*segTokenInternal, thisSegTokens []*segment, pos int) {
 
 func (node *PureGuessNode) matchForward(from int, statement []uint8) []*segment {
 	var ret []*segment
-	p := node
 
 	fCreate := func(wl int) *segment {
 		return &segment{
@@ -89,20 +91,17 @@ func (node *PureGuessNode) matchForward(from int, statement []uint8) []*segment
 		}
 	}
 
-	size := 0
-	for _, v := range statement {
-		if p.children == nil {
-			break
-		}
-		found := p.children[v]
-		if found == nil {
-			break
-		}
-		size++
-		if found.wordEnd {
-			ret = append(ret, fCreate(size))
+	if node.da != nil {
+		var state int32
+		var ok bool
+		for i, v := range statement {
+			if state, ok = node.da.Child(state, rune(v)); !ok {
+				break
+			}
+			if _, isWord := node.da.ValueOf(state); isWord {
+				ret = append(ret, fCreate(i+1))
+			}
 		}
-		p = found
 	}
 	if ret == nil {
 		ret = append(ret, fCreate(1))
@@ -111,55 +110,62 @@ func (node *PureGuessNode) matchForward(from int, statement []uint8) []*segment
 	return ret
 }
 
-func (node *PureGuessNode) addWord(word string) {
-	all := []uint8(word)
-	l := len(all)
-	p := node
-	for i, c := range all {
-		if !p.hasChild() {
-			p.children = make(map[uint8]*PureGuessNode)
-		}
-		child, ok := p.children[c]
-		if !ok {
-			child = &PureGuessNode{}
-			p.children[c] = child
-		}
-		if !child.wordEnd && (i == 0 || i == l-1) {
-			child.wordEnd = true
+// buildGuess 音节和音节的首字母都是trie中的词
+func buildGuess(words []string) (*PureGuessNode, error) {
+	keySet := map[string]struct{}{}
+	for _, word := range words {
+		keySet[word] = struct{}{}
+		keySet[word[:1]] = struct{}{}
+	}
+	// 同Guess按字节匹配
+	keys := make([][]rune, 0, len(keySet))
+	for key := range keySet {
+		runes := make([]rune, len(key))
+		for i := 0; i < len(key); i++ {
+			runes[i] = rune(key[i])
 		}
-		p = child
+		keys = append(keys, runes)
 	}
-}
-
-func (node *PureGuessNode) hasChild() bool {
-	return len(node.children) > 0
-}
-
-func LoadGuess(rootPath string) (*PureGuessNode, error) {
-	fp := path.Join(rootPath, "pinyin_alphabet.txt")
-	f, err := os.Open(fp)
+	slices.SortFunc(keys, slices.Compare[[]rune])
+	da, err := datrie.Build(keys)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	root := &PureGuessNode{}
+	return &PureGuessNode{da: da}, nil
+}
 
-	preventRepeat := map[string]struct{}{}
+func LoadGuess(rootPath string) (*PureGuessNode, error) {
+	return LoadGuessFS(os.DirFS(rootPath))
+}
 
-	scan := bufio.NewScanner(f)
-	for scan.Scan() {
-		line := scan.Text()
+// LoadGuessFS 从fsys加载拼音字母表, fsys的根目录对应dict/pinyin目录
+func LoadGuessFS(fsys fs.FS) (*PureGuessNode, error) {
+	return LoadGuessFSWithOptions(fsys, nil)
+}
 
+// LoadGuessFSWithOptions 同LoadGuessFS, 通过opts选择严格或宽松模式, 见dictload.Options
+func LoadGuessFSWithOptions(fsys fs.FS, opts *dictload.Options) (*PureGuessNode, error) {
+	var words []string
+	preventRepeat := map[string]struct{}{}
+	_, err := dictload.Load(fsys, AlphabetDictName, opts, func(line string) error {
 		word := strings.TrimSpace(line)
-
+		if word == "" {
+			return dictload.ErrSkip
+		}
+		for _, r := range word {
+			if (r < 'a' || r > 'z') && r != 'ü' {
+				return errors.New("bad pinyin:" + word)
+			}
+		}
 		if _, ok := preventRepeat[word]; ok {
-			log.Printf("%s is repeat in %s\n", line, fp)
-			continue
+			return dictload.ErrDuplicate
 		}
 		preventRepeat[word] = struct{}{}
-		root.addWord(word)
+		words = append(words, word)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	return root, scan.Err()
+	return buildGuess(words)
 }
@@ -0,0 +1,178 @@
+// Package pyjieba 提供与python jieba同名、同语义的接口, 方便把python中的原型直接迁移到go.
+//
+// python jieba的精确模式cut对应jieba.ModeSearch, cut_all对应jieba.ModeFull, cut_for_search在精确模式的结果上输出词典中的2字、3字子词,
+// 顺序与python一致: 先输出子词, 再输出原词. python中返回生成器的函数这里返回切片, Cut和Lcut相同.
+// 与python的差异: 匹配词典前会把大写字母转小写, 输出的仍是原文; 用户词典中有空白的词要用双引号括起来, 如 "Edu Trust认证" 2000.
+// jieba.SegmentHandler加载的user目录下的用户词典也会参与分词, python默认没有用户词典, 结果可能不同.
+package pyjieba
+
+import (
+	"io"
+	"math"
+	"os"
+
+	"github.com/rolandhe/jiebag/jieba"
+)
+
+// Tokenize的mode, 同python jieba.tokenize的mode参数
+const (
+	ModeDefault = "default"
+	ModeSearch  = "search"
+)
+
+// Token Tokenize的结果, Start/End是rune偏移, 同python的(word, start, end)
+type Token struct {
+	Word  string
+	Start int
+	End   int
+}
+
+// Tokenizer 同python的jieba.Tokenizer, 可以并发调用
+type Tokenizer struct {
+	handler *jieba.SegmentHandler
+}
+
+// NewTokenizer 使用handler的词典, 同python jieba.Tokenizer(dictionary=...)
+func NewTokenizer(handler *jieba.SegmentHandler) *Tokenizer {
+	return &Tokenizer{handler: handler}
+}
+
+func (t *Tokenizer) segment(sentence string, mode jieba.ModeStyle, hmm bool) []*jieba.SegToken {
+	// python不做全角转半角, 连续的单字逐字输出
+	return merge(t.handler.SegParagraphWithOptions(sentence, &jieba.SegOptions{
+		Mode:               mode,
+		DisableHmm:         !hmm,
+		SplitSingleRuns:    true,
+		KeepFullWidth:      true,
+		KeepFullWidthSpace: true,
+	}))
+}
+
+// merge python把\r\n作为一个词
+func merge(tokens []*jieba.SegToken) []*jieba.SegToken {
+	result := tokens[:0]
+	for _, token := range tokens {
+		if n := len(result); n > 0 && token.Text == "\n" && result[n-1].Text == "\r" && result[n-1].End == token.Start {
+			result[n-1] = &jieba.SegToken{Word: "\r\n", Text: "\r\n", Start: result[n-1].Start, End: token.End}
+			continue
+		}
+		result = append(result, token)
+	}
+	return result
+}
+
+func words(tokens []*jieba.SegToken) []string {
+	result := make([]string, len(tokens))
+	for i, token := range tokens {
+		result[i] = token.Text
+	}
+	return result
+}
+
+// Cut 同jieba.cut, cutAll为true时是全模式, 否则是精确模式, hmm同python的HMM参数
+func (t *Tokenizer) Cut(sentence string, cutAll bool, hmm bool) []string {
+	mode := jieba.ModeSearch
+	if cutAll {
+		mode = jieba.ModeFull
+	}
+	return words(t.segment(sentence, mode, hmm))
+}
+
+// Lcut 同jieba.lcut
+func (t *Tokenizer) Lcut(sentence string, cutAll bool, hmm bool) []string {
+	return t.Cut(sentence, cutAll, hmm)
+}
+
+// CutForSearch 同jieba.cut_for_search
+func (t *Tokenizer) CutForSearch(sentence string, hmm bool) []string {
+	tokens := t.Tokenize(sentence, ModeSearch, hmm)
+	result := make([]string, len(tokens))
+	for i, token := range tokens {
+		result[i] = token.Word
+	}
+	return result
+}
+
+// LcutForSearch 同jieba.lcut_for_search
+func (t *Tokenizer) LcutForSearch(sentence string, hmm bool) []string {
+	return t.CutForSearch(sentence, hmm)
+}
+
+// Tokenize 同jieba.tokenize, mode不是ModeDefault时都按ModeSearch处理, 与python一致
+func (t *Tokenizer) Tokenize(sentence string, mode string, hmm bool) []Token {
+	tokens := t.segment(sentence, jieba.ModeSearch, hmm)
+	result := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if mode != ModeDefault {
+			result = t.appendGrams(result, []rune(token.Text), []rune(token.Word), token.Start)
+		}
+		result = append(result, Token{Word: token.Text, Start: token.Start, End: token.End})
+	}
+	return result
+}
+
+// appendGrams 长度大于2的词输出词典中的2字子词, 大于3的词再输出3字子词. normalized是规范化后的词, 用于匹配词典
+func (t *Tokenizer) appendGrams(result []Token, word, normalized []rune, start int) []Token {
+	for n := 2; n <= 3; n++ {
+		if len(word) <= n || len(normalized) != len(word) {
+			break
+		}
+		for i := 0; i+n <= len(word); i++ {
+			if freq, ok := t.handler.Freq(string(normalized[i : i+n])); ok && freq > 0 {
+				result = append(result, Token{Word: string(word[i : i+n]), Start: start + i, End: start + i + n})
+			}
+		}
+	}
+	return result
+}
+
+// LoadUserdict 同jieba.load_userdict, 每行: 词 [词频] [词性], 词频和词性都可以省略, 省略词频时使用SuggestFreq计算.
+// 格式同jieba包的用户词典, 词中有空白时要用双引号括起来, 格式错误的行返回带行号的错误
+func (t *Tokenizer) LoadUserdict(r io.Reader) error {
+	return t.handler.LoadUserDict(r, "userdict")
+}
+
+// LoadUserdictFile 从文件加载用户词典, 见LoadUserdict
+func (t *Tokenizer) LoadUserdictFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return t.handler.LoadUserDict(f, name)
+}
+
+// AddWord 同jieba.add_word, freq小于0时同python的freq=None, 使用SuggestFreq计算; freq为0时同DelWord
+func (t *Tokenizer) AddWord(word string, freq int, tag string) error {
+	if freq == 0 {
+		return t.DelWord(word)
+	}
+	if freq < 0 {
+		freq = 0
+	}
+	return t.handler.AddWord(word, float64(freq), tag)
+}
+
+// DelWord 同jieba.del_word, 词不存在时不报错
+func (t *Tokenizer) DelWord(word string) error {
+	_, err := t.handler.DelWord(word)
+	return err
+}
+
+// SuggestFreq 同jieba.suggest_freq, segment只有一个时使它作为一个整体, 多个时使它们被切开
+func (t *Tokenizer) SuggestFreq(tune bool, segment ...string) (int, error) {
+	freq, err := t.handler.SuggestFreq(tune, segment...)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Round(freq)), nil
+}
+
+// GetFREQ 同jieba.get_FREQ, 词不存在时返回false. python中词的前缀也有词频0, 这里不存在
+func (t *Tokenizer) GetFREQ(word string) (int, bool) {
+	freq, ok := t.handler.Freq(word)
+	if !ok {
+		return 0, false
+	}
+	return int(math.Round(freq)), true
+}
@@ -0,0 +1,178 @@
+package pyjieba
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/rolandhe/jiebag/jieba"
+)
+
+// loadTokenizer 只加载基础词典和hmm模型, 同python jieba.Tokenizer(dictionary=dictPath)
+func loadTokenizer(t *testing.T, dictPath string) *Tokenizer {
+	fsys := fstest.MapFS{jieba.UserDictDirName + "/empty.dict": &fstest.MapFile{}}
+	for name, fp := range map[string]string{
+		jieba.BaseDictName: dictPath,
+		jieba.BaseProbName: "../dict/prob_emit.txt",
+	} {
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	handler, err := jieba.NewSegmentHandlerFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTokenizer(handler)
+}
+
+func formatTokens(tokens []Token) string {
+	items := make([]string, len(tokens))
+	for i, token := range tokens {
+		items[i] = fmt.Sprintf("%s:%d:%d", token.Word, token.Start, token.End)
+	}
+	return strings.Join(items, "/")
+}
+
+// pythonDict 仓库的dict目录, 包括pos等模型, 但是没有用户词典, 同python jieba默认的Tokenizer
+type pythonDict struct {
+	fs.FS
+}
+
+var emptyUserDict = fstest.MapFS{jieba.UserDictDirName + "/empty.dict": &fstest.MapFile{}}
+
+func (d pythonDict) Open(name string) (fs.File, error) {
+	if name == jieba.UserDictDirName || strings.HasPrefix(name, jieba.UserDictDirName+"/") {
+		return emptyUserDict.Open(name)
+	}
+	return d.FS.Open(name)
+}
+
+// TestGolden 对比python jieba的输出, 使用仓库的dict目录, 其中的dict.txt是python jieba的默认词典
+func TestGolden(t *testing.T) {
+	if _, err := os.Stat("../dict/" + jieba.PosModelDirName); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := jieba.NewSegmentHandlerFS(pythonDict{os.DirFS("../dict")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenizer := NewTokenizer(handler)
+	f, err := os.Open("testdata/golden.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	scan := bufio.NewScanner(f)
+	for scan.Scan() {
+		line := scan.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		items := strings.Split(line, "\t")
+		if len(items) != 3 {
+			t.Fatalf("bad golden line: %s", line)
+		}
+		fn, arg, expect := items[0], items[1], items[2]
+		var got string
+		switch fn {
+		case "cut":
+			got = strings.Join(tokenizer.Cut(arg, false, true), "/")
+		case "cut_no_hmm":
+			got = strings.Join(tokenizer.Cut(arg, false, false), "/")
+		case "cut_all":
+			got = strings.Join(tokenizer.Cut(arg, true, true), "/")
+		case "cut_for_search":
+			got = strings.Join(tokenizer.CutForSearch(arg, true), "/")
+		case "cut_for_search_no_hmm":
+			got = strings.Join(tokenizer.CutForSearch(arg, false), "/")
+		case "tokenize":
+			got = formatTokens(tokenizer.Tokenize(arg, ModeDefault, true))
+		case "tokenize_search":
+			got = formatTokens(tokenizer.Tokenize(arg, ModeSearch, true))
+		case "suggest_freq_tune":
+			freq, err := tokenizer.SuggestFreq(true, strings.Split(arg, "/")...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = strconv.Itoa(freq)
+		case "load_userdict":
+			if err = tokenizer.LoadUserdictFile("testdata/" + arg); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("unknown func %s", fn)
+		}
+		fmt.Println(fn, arg, got)
+		if got != expect {
+			t.Errorf("%s(%s): expect %s, got %s", fn, arg, expect, got)
+		}
+	}
+	if err = scan.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	tokenizer := loadTokenizer(t, "../test/jieba/dict.txt")
+
+	if got := strings.Join(tokenizer.Lcut("我来到北京清华大学", true, false), "/"); got != "我/来到/北京/清华/清华大学/华大/大学" {
+		t.Errorf("bad cut_all %s", got)
+	}
+	// 子词在原词之前, 先输出2字子词, 再输出3字子词
+	if got := strings.Join(tokenizer.LcutForSearch("中华人民共和国", false), "/"); got != "中华/华人/人民/共和/共和国/中华人民共和国" {
+		t.Errorf("bad cut_for_search %s", got)
+	}
+	// 不是default的mode都按search处理
+	if got := formatTokens(tokenizer.Tokenize("我爱中华人民共和国", "other", false)); got != "我:0:1/爱:1:2/中华:2:4/华人:3:5/人民:4:6/共和:6:8/共和国:6:9/中华人民共和国:2:9" {
+		t.Errorf("bad tokenize %s", got)
+	}
+	if got := strings.Join(tokenizer.Cut("A\r\nb", false, false), "|"); got != "A|\r\n|b" {
+		t.Errorf("bad cut %q", got)
+	}
+
+	userDict := "\ufeff小清新 3 a\n\"Edu Trust认证\" 2000\n又拍云 nt\n\n长江大桥 0\n"
+	if err := tokenizer.LoadUserdict(strings.NewReader(userDict)); err != nil {
+		t.Fatal(err)
+	}
+	// 格式错误的行返回带行号的错误
+	if err := tokenizer.LoadUserdict(strings.NewReader("好用 300\nEdu Trust认证 2000\n")); err == nil || !strings.HasPrefix(err.Error(), "userdict:2:") {
+		t.Errorf("expect line error, got %v", err)
+	}
+	if freq, ok := tokenizer.GetFREQ("小清新"); !ok || freq != 3 {
+		t.Errorf("bad freq %d", freq)
+	}
+	if freq, ok := tokenizer.GetFREQ("edu trust认证"); !ok || freq != 2000 {
+		t.Errorf("bad freq %d", freq)
+	}
+	// 没有词频时使用suggest_freq
+	if freq, ok := tokenizer.GetFREQ("又拍云"); !ok || freq != 1 {
+		t.Errorf("bad freq %d", freq)
+	}
+	// 词频为0时删除
+	if _, ok := tokenizer.GetFREQ("长江大桥"); ok {
+		t.Error("expect deleted")
+	}
+	if got := strings.Join(tokenizer.Cut("南京市长江大桥", false, false), "/"); got != "南京市/长江/大桥" {
+		t.Errorf("bad cut %s", got)
+	}
+
+	freq, err := tokenizer.SuggestFreq(true, "长江大桥")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := tokenizer.GetFREQ("长江大桥"); got != freq {
+		t.Errorf("expect freq %d, got %d", freq, got)
+	}
+	if got := strings.Join(tokenizer.Cut("南京市长江大桥", false, false), "/"); got != "南京市/长江大桥" {
+		t.Errorf("bad cut %s", got)
+	}
+}
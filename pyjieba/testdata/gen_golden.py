@@ -0,0 +1,42 @@
+# 使用python jieba重新生成golden.txt的输出列:
+#   pip install jieba
+#   python gen_golden.py path/to/dict.txt < golden.txt > golden.new && mv golden.new golden.txt
+import os
+import sys
+
+import jieba
+
+tokenizer = jieba.Tokenizer(dictionary=sys.argv[1])
+here = os.path.dirname(os.path.abspath(__file__))
+
+
+def run(func, arg):
+    if func == "cut":
+        return "/".join(tokenizer.cut(arg))
+    if func == "cut_no_hmm":
+        return "/".join(tokenizer.cut(arg, HMM=False))
+    if func == "cut_all":
+        return "/".join(tokenizer.cut(arg, cut_all=True))
+    if func == "cut_for_search":
+        return "/".join(tokenizer.cut_for_search(arg))
+    if func == "cut_for_search_no_hmm":
+        return "/".join(tokenizer.cut_for_search(arg, HMM=False))
+    if func in ("tokenize", "tokenize_search"):
+        mode = "search" if func == "tokenize_search" else "default"
+        return "/".join("%s:%d:%d" % t for t in tokenizer.tokenize(arg, mode=mode))
+    if func == "suggest_freq_tune":
+        segment = arg.split("/")
+        return str(tokenizer.suggest_freq(segment[0] if len(segment) == 1 else tuple(segment), True))
+    if func == "load_userdict":
+        tokenizer.load_userdict(os.path.join(here, arg))
+        return ""
+    raise ValueError("unknown func: " + func)
+
+
+for line in sys.stdin:
+    line = line.rstrip("\n")
+    if not line or line.startswith("#"):
+        print(line)
+        continue
+    func, arg = line.split("\t")[:2]
+    print("\t".join([func, arg, run(func, arg)]))